@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/xianlianghe0123/anycache/cache"
 )
 
 type (
-	strategy int
+	strategy       int
+	cacheErrorMode int
 )
 
 const (
@@ -19,47 +22,113 @@ const (
 	StrategyCacheOnly
 )
 
+func (s strategy) String() string {
+	switch s {
+	case StrategyCacheFirst:
+		return "cache_first"
+	case StrategySourceFirst:
+		return "source_first"
+	case StrategyCacheOnly:
+		return "cache_only"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// CacheErrorFallthrough treats a failing cache like a miss and loads from
+	// the source.
+	CacheErrorFallthrough cacheErrorMode = iota
+	// CacheErrorSurface returns the cache error without loading.
+	CacheErrorSurface
+)
+
 type IAnyCache[K any, V any] interface {
 	WithGenKeyFunc(genKeyFunc func(t K) string) IAnyCache[K, V]
 	WithStrategy(strategy strategy) IAnyCache[K, V]
+	WithCacheErrorMode(mode cacheErrorMode) IAnyCache[K, V]
 	WithExpiration(expiration time.Duration) IAnyCache[K, V]
+	WithSoftExpiration(expiration time.Duration) IAnyCache[K, V]
+	WithNotFoundExpiration(expiration time.Duration) IAnyCache[K, V]
+	WithExpirationJitterPercent(percent float64) IAnyCache[K, V]
+	WithExpirationJitterRange(jitter time.Duration) IAnyCache[K, V]
+	WithRandSource(source RandSource) IAnyCache[K, V]
 	WithNameSpace(namespace string) IAnyCache[K, V]
 	WithEmptyValue(v V) IAnyCache[K, V]
 	WithLoader(loader Loader[K, V]) IAnyCache[K, V]
 	WithLoadFunc(loader loadFunc[K, V]) IAnyCache[K, V]
+	WithTTLLoader(loader TTLLoader[K, V]) IAnyCache[K, V]
+	WithTTLLoadFunc(loader ttlLoadFunc[K, V]) IAnyCache[K, V]
 	WithBatchLoader(batchLoader BatchLoader[K, V]) IAnyCache[K, V]
 	WithBatchLoadFunc(batchLoader batchLoadFunc[K, V]) IAnyCache[K, V]
+	WithResultBatchLoader(batchLoader ResultBatchLoader[K, V]) IAnyCache[K, V]
+	WithResultBatchLoadFunc(batchLoader resultBatchLoadFunc[K, V]) IAnyCache[K, V]
+	WithSingleflight(enabled bool) IAnyCache[K, V]
+	WithDataLoader(window time.Duration, maxBatchSize int) IAnyCache[K, V]
+	WithFilter(filter Filter) IAnyCache[K, V]
+	WithFilterSeed(seed func(yield func(K) bool)) IAnyCache[K, V]
+	WithRefreshAhead(options RefreshAheadOptions) IAnyCache[K, V]
+	WithStaleIfError(grace time.Duration) IAnyCache[K, V]
+	WithLoadTimeout(timeout time.Duration) IAnyCache[K, V]
+	WithTracer(tracer Tracer) IAnyCache[K, V]
+	WithLogger(logger *slog.Logger) IAnyCache[K, V]
+	WithLogOptions(options LogOptions) IAnyCache[K, V]
 	Build() Fetcher[K, V]
 }
 
 type anyCache[K any, V any] struct {
-	strategy    strategy
-	genKeyFunc  func(t K) string
-	cache       cache.Cacher[V]
-	loader      Loader[K, V]
-	batchLoader BatchLoader[K, V]
+	strategy       strategy
+	cacheErrorMode cacheErrorMode
+	genKeyFunc     func(t K) string
+	cache          cache.Cacher[V]
+	loader         TTLLoader[K, V]
+	batchLoader    ResultBatchLoader[K, V]
+	flight         *group[Result[V]]
+	dataLoader     *dataLoader[K, V]
+	filter         Filter
+	filterSeed     func(yield func(K) bool)
+	revalidator    *revalidator
+	refresher      *refresher[K, V]
 
 	namespace  string
 	emptyValue V
 	expiration time.Duration
+	// softExpiration enables stale-while-revalidate when positive
+	softExpiration time.Duration
+	// notFoundExpiration enables negative caching when positive
+	notFoundExpiration time.Duration
+	// staleGrace enables serving expired values on load failures when positive
+	staleGrace    time.Duration
+	loadTimeout   time.Duration
+	jitterPercent float64
+	jitterRange   time.Duration
+	randSource    RandSource
 
-	hit    int64
-	source int64
+	stats      *stats
+	tracer     *tracer
+	slogger    *slog.Logger
+	logOptions LogOptions
+	logger     *logger
 }
 
 func New[K any, V any](cache cache.Cacher[V]) IAnyCache[K, V] {
 	if cache == nil {
 		panic("nil cache")
 	}
-	return &anyCache[K, V]{
+	a := &anyCache[K, V]{
 		strategy:    StrategyCacheFirst,
 		genKeyFunc:  func(k K) string { return fmt.Sprint(k) },
-		cache:       cache,
 		loader:      nil,
 		batchLoader: nil,
 		namespace:   "",
 		expiration:  0,
+		randSource:  globalRandSource{},
+		revalidator: newRevalidator(),
+		stats:       newStats(),
+		tracer:      &tracer{},
 	}
+	a.cache = &instrumentedCacher[V]{cacher: cache, stats: a.stats, tracer: a.tracer}
+	return a
 }
 
 func (a *anyCache[K, V]) WithGenKeyFunc(genKeyFunc func(t K) string) IAnyCache[K, V] {
@@ -72,11 +141,66 @@ func (a *anyCache[K, V]) WithStrategy(strategy strategy) IAnyCache[K, V] {
 	return a
 }
 
+func (a *anyCache[K, V]) WithCacheErrorMode(mode cacheErrorMode) IAnyCache[K, V] {
+	a.cacheErrorMode = mode
+	return a
+}
+
 func (a *anyCache[K, V]) WithExpiration(expiration time.Duration) IAnyCache[K, V] {
 	a.expiration = expiration
 	return a
 }
 
+// WithSoftExpiration makes values stale after expiration: they are still
+// returned until the entry expires, but the first read of a stale value
+// reloads it in the background. It only applies to entries expiring later than
+// expiration, or never. Staleness is read from the entry metadata, Build panics
+// unless the cache declares to keep it with cache.MetadataKeeper.
+func (a *anyCache[K, V]) WithSoftExpiration(expiration time.Duration) IAnyCache[K, V] {
+	a.softExpiration = expiration
+	return a
+}
+
+// WithNotFoundExpiration caches the keys loaders report with ErrNotFound for
+// expiration, so they are answered with ErrNotFound without loading again.
+// Tombstones are recorded in the entry metadata, Build panics unless the cache
+// declares to keep it with cache.MetadataKeeper.
+func (a *anyCache[K, V]) WithNotFoundExpiration(expiration time.Duration) IAnyCache[K, V] {
+	a.notFoundExpiration = expiration
+	return a
+}
+
+// WithExpirationJitterPercent spreads every expiration randomly by up to
+// percent of it in either direction, so keys written together do not expire
+// together.
+func (a *anyCache[K, V]) WithExpirationJitterPercent(percent float64) IAnyCache[K, V] {
+	if percent < 0 || percent > 100 {
+		panic("percent must be in [0, 100]")
+	}
+	a.jitterPercent, a.jitterRange = percent, 0
+	return a
+}
+
+// WithExpirationJitterRange spreads every expiration randomly by up to jitter
+// in either direction.
+func (a *anyCache[K, V]) WithExpirationJitterRange(jitter time.Duration) IAnyCache[K, V] {
+	if jitter < 0 {
+		panic("jitter must not be negative")
+	}
+	a.jitterPercent, a.jitterRange = 0, jitter
+	return a
+}
+
+// WithRandSource replaces the random source of the expiration jitter, e.g. with
+// a seeded *rand.Rand for deterministic tests.
+func (a *anyCache[K, V]) WithRandSource(source RandSource) IAnyCache[K, V] {
+	if source == nil {
+		panic("source is nil")
+	}
+	a.randSource = &lockedRandSource{source: source}
+	return a
+}
+
 func (a *anyCache[K, V]) WithNameSpace(namespace string) IAnyCache[K, V] {
 	a.namespace = namespace
 	return a
@@ -91,67 +215,228 @@ func (a *anyCache[K, V]) WithLoader(loader Loader[K, V]) IAnyCache[K, V] {
 	if loader == nil {
 		panic("loader is nil")
 	}
-	a.loader = loader
-	return a
+	if ttlLoader, ok := loader.(TTLLoader[K, V]); ok {
+		return a.WithTTLLoader(ttlLoader)
+	}
+	return a.WithTTLLoadFunc(func(ctx context.Context, key K) (V, time.Duration, error) {
+		value, err := loader.Load(ctx, key)
+		return value, 0, err
+	})
 }
 
 func (a *anyCache[K, V]) WithLoadFunc(loader loadFunc[K, V]) IAnyCache[K, V] {
 	return a.WithLoader(loader)
 }
 
+func (a *anyCache[K, V]) WithTTLLoader(loader TTLLoader[K, V]) IAnyCache[K, V] {
+	if loader == nil {
+		panic("loader is nil")
+	}
+	a.loader = loader
+	return a
+}
+
+func (a *anyCache[K, V]) WithTTLLoadFunc(loader ttlLoadFunc[K, V]) IAnyCache[K, V] {
+	return a.WithTTLLoader(loader)
+}
+
 func (a *anyCache[K, V]) WithBatchLoader(batchLoader BatchLoader[K, V]) IAnyCache[K, V] {
 	if batchLoader == nil {
 		panic("batchLoader is nil")
 	}
-	a.batchLoader = batchLoader
-	return a
+	return a.WithResultBatchLoadFunc(func(ctx context.Context, keys []K) ([]LoadResult[V], error) {
+		values, err := batchLoader.BatchLoad(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != len(keys) {
+			return nil, lengthMismatch(len(keys), len(values))
+		}
+		results := make([]LoadResult[V], len(values))
+		for i, value := range values {
+			results[i].Value = value
+		}
+		return results, nil
+	})
 }
 
 func (a *anyCache[K, V]) WithBatchLoadFunc(batchLoader batchLoadFunc[K, V]) IAnyCache[K, V] {
 	return a.WithBatchLoader(batchLoader)
 }
 
+func (a *anyCache[K, V]) WithResultBatchLoader(batchLoader ResultBatchLoader[K, V]) IAnyCache[K, V] {
+	if batchLoader == nil {
+		panic("batchLoader is nil")
+	}
+	a.batchLoader = batchLoader
+	return a
+}
+
+func (a *anyCache[K, V]) WithResultBatchLoadFunc(batchLoader resultBatchLoadFunc[K, V]) IAnyCache[K, V] {
+	return a.WithResultBatchLoader(batchLoader)
+}
+
+// WithSingleflight makes concurrent loads of the same key share one Load call
+// and its result. The shared call is not canceled with the caller starting it,
+// every caller stops waiting when its own context is done.
+func (a *anyCache[K, V]) WithSingleflight(enabled bool) IAnyCache[K, V] {
+	if enabled {
+		a.flight = newGroup[Result[V]]()
+	} else {
+		a.flight = nil
+	}
+	return a
+}
+
+// WithDataLoader batches the loads of concurrent Get misses: keys are
+// collected for up to window, or until maxBatchSize distinct keys are pending,
+// and loaded with one BatchLoad call. A maxBatchSize of 0 means no limit.
+func (a *anyCache[K, V]) WithDataLoader(window time.Duration, maxBatchSize int) IAnyCache[K, V] {
+	if window <= 0 {
+		panic("window must be positive")
+	}
+	if maxBatchSize < 0 {
+		panic("maxBatchSize must not be negative")
+	}
+	a.dataLoader = &dataLoader[K, V]{
+		window:       window,
+		maxBatchSize: maxBatchSize,
+	}
+	return a
+}
+
+// WithFilter guards the fetcher with a membership filter: keys it does not
+// contain are answered with ErrNotFound without touching the cache or the
+// loader. The filter learns the keys that are set or loaded, the existing ones
+// must be seeded with WithFilterSeed.
+func (a *anyCache[K, V]) WithFilter(filter Filter) IAnyCache[K, V] {
+	a.filter = filter
+	return a
+}
+
+// WithFilterSeed adds every key yielded by seed to the filter on Build.
+func (a *anyCache[K, V]) WithFilterSeed(seed func(yield func(K) bool)) IAnyCache[K, V] {
+	a.filterSeed = seed
+	return a
+}
+
+// WithRefreshAhead tracks the reads of cached keys and reloads the hot ones in
+// the background before they expire, until the fetcher is closed. Expirations
+// are read from the entry metadata, Build panics unless the cache declares to
+// keep it with cache.MetadataKeeper.
+func (a *anyCache[K, V]) WithRefreshAhead(options RefreshAheadOptions) IAnyCache[K, V] {
+	a.refresher = newRefresher[K, V](options)
+	return a
+}
+
+// WithStaleIfError keeps entries for grace past their expiration. Until then,
+// an expired value is still returned, marked stale, when reloading it fails.
+// Expirations are read from the entry metadata, Build panics unless the cache
+// declares to keep it with cache.MetadataKeeper.
+func (a *anyCache[K, V]) WithStaleIfError(grace time.Duration) IAnyCache[K, V] {
+	a.staleGrace = grace
+	return a
+}
+
+// WithLoadTimeout bounds every Load and BatchLoad call by timeout, loaders are
+// expected to give up once their context is done.
+func (a *anyCache[K, V]) WithLoadTimeout(timeout time.Duration) IAnyCache[K, V] {
+	a.loadTimeout = timeout
+	return a
+}
+
 func (a *anyCache[K, V]) Build() Fetcher[K, V] {
 	if a.loader == nil && a.batchLoader == nil {
 		panic("no loader")
 	}
+	if a.notFoundExpiration > 0 && !cache.KeepsMetadata(a.cache) {
+		panic("not found expiration needs a cache keeping metadata")
+	}
+	if a.staleGrace > 0 && !cache.KeepsMetadata(a.cache) {
+		panic("stale if error needs a cache keeping metadata")
+	}
+	if a.softExpiration > 0 && !cache.KeepsMetadata(a.cache) {
+		panic("soft expiration needs a cache keeping metadata")
+	}
+	if a.refresher != nil && !cache.KeepsMetadata(a.cache) {
+		panic("refresh ahead needs a cache keeping metadata")
+	}
 	if a.loader == nil {
-		a.WithLoadFunc(func(ctx context.Context, key K) (V, error) {
-			values, err := a.batchLoader.BatchLoad(ctx, []K{key})
+		a.WithTTLLoadFunc(func(ctx context.Context, key K) (V, time.Duration, error) {
+			results, err := a.batchLoader.BatchLoadResults(ctx, []K{key})
 			if err != nil {
-				return a.emptyValue, err
+				return a.emptyValue, 0, err
+			}
+			if len(results) != 1 {
+				return a.emptyValue, 0, lengthMismatch(1, len(results))
 			}
-			if len(values) == 0 {
-				return a.emptyValue, errors.New("not found")
+			if results[0].Err != nil {
+				return a.emptyValue, 0, results[0].Err
 			}
-			return values[0], nil
+			return results[0].Value, results[0].TTL, nil
 		})
 	}
 	if a.batchLoader == nil {
-		a.WithBatchLoadFunc(func(ctx context.Context, keys []K) ([]V, error) {
-			values := make([]V, len(keys))
+		a.WithResultBatchLoadFunc(func(ctx context.Context, keys []K) ([]LoadResult[V], error) {
+			results := make([]LoadResult[V], len(keys))
 			for i, key := range keys {
-				value, err := a.loader.Load(ctx, key)
-				if err != nil {
-					values[i] = a.emptyValue
-				} else {
-					values[i] = value
-				}
+				results[i].Value, results[i].TTL, results[i].Err = a.loader.LoadWithTTL(ctx, key)
 			}
-			return values, nil
+			return results, nil
 		})
 	}
+	if a.filterSeed != nil {
+		if a.filter == nil {
+			panic("no filter to seed")
+		}
+		a.filterSeed(func(key K) bool {
+			a.filter.Add(a.buildKey(key))
+			return true
+		})
+	}
+	if a.dataLoader != nil {
+		a.dataLoader.buildKey = a.buildKey
+		a.dataLoader.batchLoad = a.mGetSource
+		a.dataLoader.emptyValue = a.emptyValue
+	}
+	if a.refresher != nil {
+		a.refresher.refresh = a.backgroundRefresh
+		a.refresher.start()
+	}
+	if a.slogger != nil {
+		a.logger = newLogger(a.slogger.With("namespace", a.namespace), a.logOptions)
+	}
+	a.tracer.attrs = []Attribute{
+		{Key: AttrNamespace, Value: a.namespace},
+		{Key: AttrStrategy, Value: a.strategy.String()},
+	}
 	return a
 }
 
 func (a *anyCache[K, V]) Get(ctx context.Context, key K) (V, error) {
-	return a.get(ctx, key)
+	result := a.get(ctx, key)
+	return result.Value, result.Err
+}
+
+func (a *anyCache[K, V]) GetWithMeta(ctx context.Context, key K) Result[V] {
+	result := a.get(ctx, key)
+	result.withTimes(time.Now())
+	return result
 }
 
 func (a *anyCache[K, V]) MGet(ctx context.Context, keys []K) ([]V, error) {
 	return a.mGet(ctx, keys)
 }
 
+func (a *anyCache[K, V]) MGetWithMeta(ctx context.Context, keys []K) ([]Result[V], error) {
+	results, err := a.mGetResults(ctx, keys)
+	now := time.Now()
+	for i := range results {
+		results[i].withTimes(now)
+	}
+	return results, err
+}
+
 func (a *anyCache[K, V]) Set(ctx context.Context, key K, value V) error {
 	return a.mSet(ctx, []K{key}, []V{value})
 }
@@ -180,10 +465,19 @@ func (a *anyCache[K, V]) Refresh(ctx context.Context, keys ...K) error {
 	return a.refresh(ctx, keys...)
 }
 
+func (a *anyCache[K, V]) Close() error {
+	if a.refresher != nil {
+		a.refresher.close()
+	}
+	return nil
+}
+
 func (a *anyCache[K, V]) mSet(ctx context.Context, keys []K, values []V) error {
 	entries := make([]cache.Entry[V], 0, len(values))
 	for i, key := range keys {
-		entries = append(entries, a.newEntry(a.buildKey(key), values[i]))
+		cacheKey := a.buildKey(key)
+		a.addFilter(cacheKey)
+		entries = append(entries, a.newEntry(cacheKey, values[i], 0, cache.OriginSet))
 	}
 	return a.cache.Set(ctx, entries...)
 }
@@ -192,12 +486,117 @@ func (a *anyCache[K, V]) del(ctx context.Context, keys ...K) error {
 	return a.cache.Del(ctx, a.buildKeys(keys)...)
 }
 
+// refresh reloads keys and stores the loaded ones, reporting the keys that
+// failed to load after the others are stored.
 func (a *anyCache[K, V]) refresh(ctx context.Context, keys ...K) error {
-	values, err := a.batchLoader.BatchLoad(ctx, keys)
+	atomic.AddInt64(&a.stats.refreshes, 1)
+	atomic.AddInt64(&a.stats.refreshedKeys, int64(len(keys)))
+	err := a.reload(ctx, keys)
+	if err != nil {
+		atomic.AddInt64(&a.stats.refreshErrors, 1)
+	}
+	return err
+}
+
+// backgroundRefresh is refresh for the reloads no caller waits for, logging
+// their errors.
+func (a *anyCache[K, V]) backgroundRefresh(ctx context.Context, keys ...K) error {
+	err := a.refresh(ctx, keys...)
+	if err != nil {
+		a.logger.loadError(ctx, "background refresh failed", err, "keys", len(keys))
+	}
+	return err
+}
+
+func (a *anyCache[K, V]) reload(ctx context.Context, keys []K) error {
+	results, err := a.batchLoad(ctx, keys)
 	if err != nil {
 		return err
 	}
-	return a.mSet(ctx, keys, values)
+	if _, err = a.storeLoaded(ctx, keys, results, cache.OriginRefresh); err != nil {
+		return err
+	}
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil && !a.cachesNotFound(result.Err) {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// storeLoaded caches the loaded results, along with tombstones for the keys
+// reported not found when negative caching is enabled. It returns the metadata
+// of the entry stored for each key.
+func (a *anyCache[K, V]) storeLoaded(ctx context.Context, keys []K, results []LoadResult[V], origin cache.Origin) ([]cache.Metadata, error) {
+	metadata := make([]cache.Metadata, len(results))
+	entries := make([]cache.Entry[V], 0, len(results))
+	for i, result := range results {
+		var e cache.Entry[V]
+		switch {
+		case result.Err == nil:
+			cacheKey := a.buildKey(keys[i])
+			a.addFilter(cacheKey)
+			e = a.newEntry(cacheKey, result.Value, result.TTL, origin)
+		case a.cachesNotFound(result.Err):
+			e = a.newTombstone(a.buildKey(keys[i]), origin)
+		default:
+			continue
+		}
+		metadata[i] = cache.MetadataOf(e)
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return metadata, nil
+	}
+	return metadata, a.cache.Set(ctx, entries...)
+}
+
+func (a *anyCache[K, V]) cachesNotFound(err error) bool {
+	return a.notFoundExpiration > 0 && errors.Is(err, ErrNotFound)
+}
+
+// batchLoad loads keys with the batch loader, checking it returns one result
+// per key.
+func (a *anyCache[K, V]) batchLoad(ctx context.Context, keys []K) ([]LoadResult[V], error) {
+	ctx, cancel := a.withLoadTimeout(ctx)
+	defer cancel()
+	atomic.AddInt64(&a.stats.loaderCalls, 1)
+	a.stats.batchSizes.observe(int64(len(keys)))
+	ctx, span := a.tracer.start(ctx, SpanBatchLoad, len(keys))
+	start := time.Now()
+	results, err := a.batchLoader.BatchLoadResults(ctx, keys)
+	a.stats.loadLatency.observeSince(start)
+	if err == nil && len(results) != len(keys) {
+		err = lengthMismatch(len(keys), len(results))
+	}
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil && !errors.Is(result.Err, ErrNotFound) {
+			failed++
+		}
+	}
+	endSpan(span, nil, Attribute{Key: AttrFailedKeys, Value: failed})
+	return results, nil
+}
+
+// lengthMismatch fails a batch load returning a number of results other than
+// the number of keys. It is logged, like any load error, by the caller
+// swallowing it.
+func lengthMismatch(keys, results int) error {
+	return fmt.Errorf("keys and results length not equal: %d results for %d keys", results, keys)
+}
+
+// withLoadTimeout bounds ctx by the load timeout, if any.
+func (a *anyCache[K, V]) withLoadTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.loadTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, a.loadTimeout)
 }
 
 // common
@@ -216,6 +615,60 @@ func (a *anyCache[K, V]) buildKeys(keys []K) []string {
 	return cacheKeys
 }
 
-func (a *anyCache[K, V]) newEntry(key string, value V) cache.Entry[V] {
-	return cache.NewEntry(key, value, a.expiration)
+func (a *anyCache[K, V]) addFilter(key string) {
+	if a.filter != nil {
+		a.filter.Add(key)
+	}
+}
+
+// filtered reports whether key is known not to exist.
+func (a *anyCache[K, V]) filtered(key K) bool {
+	return a.filter != nil && !a.filter.Contains(a.buildKey(key))
+}
+
+// lastVersion is the version of the last entry created in this process.
+// Versions follow the wall clock in nanoseconds when it is ahead, which only
+// orders them across processes as far as the clocks agree.
+var lastVersion atomic.Uint64
+
+func nextVersion(now time.Time) uint64 {
+	for {
+		last := lastVersion.Load()
+		next := max(last+1, uint64(now.UnixNano()))
+		if lastVersion.CompareAndSwap(last, next) {
+			return next
+		}
+	}
+}
+
+// newEntry expires the entry after ttl when it is positive, never with
+// NoExpiration and after the fetcher expiration otherwise. The entry is kept
+// for the stale grace period past its expiration.
+func (a *anyCache[K, V]) newEntry(key string, value V, ttl time.Duration, origin cache.Origin) cache.Entry[V] {
+	expiration := a.expiration
+	switch {
+	case ttl == NoExpiration:
+		expiration = 0
+	case ttl > 0:
+		expiration = ttl
+	}
+	expiration = a.jitter(expiration)
+	now := time.Now()
+	md := cache.Metadata{StoredAt: now, Version: nextVersion(now), Origin: origin}
+	if expiration > 0 {
+		md.ExpiresAt = now.Add(expiration)
+	}
+	if a.softExpiration > 0 && (expiration == 0 || a.softExpiration < expiration) {
+		md.SoftExpiresAt = now.Add(a.softExpiration)
+	}
+	if expiration > 0 {
+		expiration += a.staleGrace
+	}
+	return cache.NewEntryWithMetadata(key, value, expiration, md)
+}
+
+func (a *anyCache[K, V]) newTombstone(key string, origin cache.Origin) cache.Entry[V] {
+	now := time.Now()
+	md := cache.Metadata{NotFound: true, StoredAt: now, Version: nextVersion(now), Origin: origin}
+	return cache.NewEntryWithMetadata(key, a.emptyValue, a.jitter(a.notFoundExpiration), md)
 }
@@ -84,7 +84,7 @@ func (t *MapCache[V]) Get(ctx context.Context, key string) (cache.Entry[V], erro
 	}
 	v, ok := t.Map[key]
 	if !ok {
-		return nil, errors.New("nil")
+		return nil, cache.ErrNotFound
 	}
 	return t.newEntry(key, v), nil
 }
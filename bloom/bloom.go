@@ -0,0 +1,122 @@
+package bloom
+
+import (
+	"math"
+	"sync"
+)
+
+// Filter is a scalable Bloom filter: when the current stage is full a new one
+// with twice the capacity and a tighter false positive rate is added, keeping
+// the compound false positive rate under the configured one however many keys
+// are added. It is safe for concurrent use.
+type Filter struct {
+	mu       sync.RWMutex
+	fpRate   float64
+	capacity uint64
+	stages   []*stage
+}
+
+// tightening is the ratio between the false positive rates of consecutive
+// stages, the sum of the series stays below fpRate/(1-tightening).
+const tightening = 0.5
+
+func New(capacity uint64, fpRate float64) *Filter {
+	if capacity == 0 {
+		panic("capacity must be positive")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("fpRate must be in (0, 1)")
+	}
+	f := &Filter{
+		fpRate:   fpRate,
+		capacity: capacity,
+	}
+	f.stages = append(f.stages, newStage(capacity, fpRate*(1-tightening)))
+	return f
+}
+
+func (f *Filter) Add(key string) {
+	h1, h2 := hash(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	last := f.stages[len(f.stages)-1]
+	if last.count >= last.capacity {
+		last = newStage(last.capacity*2, last.fpRate*tightening)
+		f.stages = append(f.stages, last)
+	}
+	last.add(h1, h2)
+}
+
+// Contains reports false when key was never added, true means it probably was.
+func (f *Filter) Contains(key string) bool {
+	h1, h2 := hash(key)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, s := range f.stages {
+		if s.contains(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+type stage struct {
+	bits     []uint64
+	m        uint64
+	k        uint64
+	count    uint64
+	capacity uint64
+	fpRate   float64
+}
+
+func newStage(capacity uint64, fpRate float64) *stage {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(capacity)*math.Ln2)))
+	return &stage{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+		fpRate:   fpRate,
+	}
+}
+
+// add sets the k bits derived from h1 and h2 by double hashing, only counting
+// keys that set a new bit.
+func (s *stage) add(h1, h2 uint64) {
+	added := false
+	for i := uint64(0); i < s.k; i++ {
+		bit := (h1 + i*h2) % s.m
+		if s.bits[bit/64]&(1<<(bit%64)) == 0 {
+			s.bits[bit/64] |= 1 << (bit % 64)
+			added = true
+		}
+	}
+	if added {
+		s.count++
+	}
+}
+
+func (s *stage) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < s.k; i++ {
+		bit := (h1 + i*h2) % s.m
+		if s.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hash(key string) (uint64, uint64) {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	// derive the second hash by mixing the first, forcing it odd so the
+	// probe sequence does not degenerate
+	h2 := h ^ h>>33
+	h2 *= 0xff51afd7ed558ccd
+	h2 ^= h2 >> 33
+	return h, h2 | 1
+}
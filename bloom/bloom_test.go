@@ -0,0 +1,30 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprint("key", i))
+	}
+	for i := 0; i < 10000; i++ {
+		if !f.Contains(fmt.Sprint("key", i)) {
+			t.Fatalf("false negative for key%d", i)
+		}
+	}
+	if len(f.stages) < 2 {
+		t.Fatalf("filter did not scale, stages: %d", len(f.stages))
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Contains(fmt.Sprint("absent", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.02 {
+		t.Fatalf("false positive rate too high: %f", rate)
+	}
+}
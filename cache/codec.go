@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/codec"
+)
+
+// entryHeader starts every entry encoded with metadata. It is followed by a
+// uvarint of metadata flags, the metadata fields the flags announce in flag
+// order and the value encoded by the codec. Times are varints of unix
+// nanoseconds, versions and origins uvarints.
+const entryHeader = "\xffac\x01"
+
+const (
+	flagNotFound uint64 = 1 << iota
+	flagSoftExpiresAt
+	flagExpiresAt
+	flagStoredAt
+	flagVersion
+	flagOrigin
+
+	knownFlags = flagNotFound | flagSoftExpiresAt | flagExpiresAt | flagStoredAt | flagVersion | flagOrigin
+)
+
+// errEntryFormat reports a malformed entry as a miss, so that it is reloaded
+// and overwritten.
+var errEntryFormat = fmt.Errorf("%w: malformed entry", ErrNotFound)
+
+// MarshalEntry encodes e with its metadata for byte oriented backends storing
+// metadata, using c for the value.
+func MarshalEntry[V any](e Entry[V], c codec.Codec[V]) ([]byte, error) {
+	md := MetadataOf(e)
+	var flags uint64
+	var fields []byte
+	if md.NotFound {
+		flags |= flagNotFound
+	}
+	if !md.SoftExpiresAt.IsZero() {
+		flags |= flagSoftExpiresAt
+		fields = binary.AppendVarint(fields, md.SoftExpiresAt.UnixNano())
+	}
+	if !md.ExpiresAt.IsZero() {
+		flags |= flagExpiresAt
+		fields = binary.AppendVarint(fields, md.ExpiresAt.UnixNano())
+	}
+	if !md.StoredAt.IsZero() {
+		flags |= flagStoredAt
+		fields = binary.AppendVarint(fields, md.StoredAt.UnixNano())
+	}
+	if md.Version != 0 {
+		flags |= flagVersion
+		fields = binary.AppendUvarint(fields, md.Version)
+	}
+	if md.Origin != OriginUnknown {
+		flags |= flagOrigin
+		fields = binary.AppendUvarint(fields, uint64(md.Origin))
+	}
+	data := binary.AppendUvarint([]byte(entryHeader), flags)
+	data = append(data, fields...)
+	if md.NotFound {
+		return data, nil
+	}
+	value, err := c.Marshal(e.Value())
+	if err != nil {
+		return nil, err
+	}
+	return append(data, value...), nil
+}
+
+// UnmarshalEntry decodes data written by MarshalEntry back into an entry. Data
+// written without metadata, by c alone, is decoded by c. A malformed entry is
+// reported as ErrNotFound.
+func UnmarshalEntry[V any](key string, data []byte, expiration time.Duration, c codec.Codec[V]) (Entry[V], error) {
+	if bytes.HasPrefix(data, []byte(entryHeader)) {
+		return unmarshalEnvelope(key, data[len(entryHeader):], expiration, c)
+	}
+	value, err := c.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewEntry(key, value, expiration), nil
+}
+
+func unmarshalEnvelope[V any](key string, data []byte, expiration time.Duration, c codec.Codec[V]) (Entry[V], error) {
+	flags, n := binary.Uvarint(data)
+	if n <= 0 || flags&^knownFlags != 0 {
+		return nil, errEntryFormat
+	}
+	data = data[n:]
+	var md Metadata
+	var err error
+	md.NotFound = flags&flagNotFound != 0
+	if flags&flagSoftExpiresAt != 0 {
+		if md.SoftExpiresAt, data, err = readTime(data); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagExpiresAt != 0 {
+		if md.ExpiresAt, data, err = readTime(data); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagStoredAt != 0 {
+		if md.StoredAt, data, err = readTime(data); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagVersion != 0 {
+		if md.Version, data, err = readUvarint(data); err != nil {
+			return nil, err
+		}
+	}
+	if flags&flagOrigin != 0 {
+		var origin uint64
+		if origin, data, err = readUvarint(data); err != nil {
+			return nil, err
+		}
+		md.Origin = Origin(origin)
+	}
+	var value V
+	if !md.NotFound {
+		if value, err = c.Unmarshal(data); err != nil {
+			return nil, err
+		}
+	}
+	return NewEntryWithMetadata(key, value, expiration, md), nil
+}
+
+func readTime(data []byte) (time.Time, []byte, error) {
+	nanos, n := binary.Varint(data)
+	if n <= 0 {
+		return time.Time{}, nil, errEntryFormat
+	}
+	return time.Unix(0, nanos), data[n:], nil
+}
+
+func readUvarint(data []byte) (uint64, []byte, error) {
+	v, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, errEntryFormat
+	}
+	return v, data[n:], nil
+}
+
+type CodecOption func(o *codecOptions)
+
+type codecOptions struct {
+	metadata bool
+}
+
+// WithMetadata encodes the entry metadata along with the values, see
+// MarshalEntry. By default only the values are encoded, the metadata being
+// kept as far as the Cacher of raw bytes keeps it.
+func WithMetadata() CodecOption {
+	return func(o *codecOptions) {
+		o.metadata = true
+	}
+}
+
+type codecCacher[V any] struct {
+	cacher   Cacher[[]byte]
+	codec    codec.Codec[V]
+	metadata bool
+}
+
+// NewCodecCacher adapts a Cacher of raw bytes into a typed Cacher, encoding
+// values with c.
+func NewCodecCacher[V any](bytesCacher Cacher[[]byte], c codec.Codec[V], opts ...CodecOption) Cacher[V] {
+	if bytesCacher == nil {
+		panic("nil cache")
+	}
+	if c == nil {
+		panic("nil codec")
+	}
+	o := &codecOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &codecCacher[V]{
+		cacher:   bytesCacher,
+		codec:    c,
+		metadata: o.metadata,
+	}
+}
+
+func (c *codecCacher[V]) Get(ctx context.Context, key string) (Entry[V], error) {
+	e, err := c.cacher.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.decode(e)
+}
+
+func (c *codecCacher[V]) MGet(ctx context.Context, keys []string) ([]Entry[V], error) {
+	raw, err := c.cacher.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry[V], len(raw))
+	for i, e := range raw {
+		if e == nil {
+			continue
+		}
+		entries[i], err = c.decode(e)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (c *codecCacher[V]) Set(ctx context.Context, entries ...Entry[V]) error {
+	raw := make([]Entry[[]byte], 0, len(entries))
+	for _, e := range entries {
+		if c.metadata {
+			data, err := MarshalEntry(e, c.codec)
+			if err != nil {
+				return err
+			}
+			raw = append(raw, NewEntry(e.Key(), data, e.Expiration()))
+			continue
+		}
+		data, err := c.codec.Marshal(e.Value())
+		if err != nil {
+			return err
+		}
+		raw = append(raw, NewEntryWithMetadata(e.Key(), data, e.Expiration(), MetadataOf(e)))
+	}
+	return c.cacher.Set(ctx, raw...)
+}
+
+func (c *codecCacher[V]) Del(ctx context.Context, keys ...string) error {
+	return c.cacher.Del(ctx, keys...)
+}
+
+func (c *codecCacher[V]) KeepsMetadata() bool {
+	return c.metadata || KeepsMetadata(c.cacher)
+}
+
+func (c *codecCacher[V]) decode(e Entry[[]byte]) (Entry[V], error) {
+	if c.metadata {
+		return UnmarshalEntry(e.Key(), e.Value(), e.Expiration(), c.codec)
+	}
+	value, err := c.codec.Unmarshal(e.Value())
+	if err != nil {
+		return nil, err
+	}
+	return NewEntryWithMetadata(e.Key(), value, e.Expiration(), MetadataOf(e)), nil
+}
@@ -0,0 +1,132 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+	"github.com/xianlianghe0123/anycache/codec"
+)
+
+var ctx = context.Background()
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestCodecCacher(t *testing.T) {
+	raw := memory.New[[]byte]()
+	c := cache.NewCodecCacher[user](raw, codec.JSON[user]{})
+	_ = c.Set(ctx, cache.NewEntry("u1", user{ID: 1, Name: "a"}, 0))
+	e, err := raw.Get(ctx, "u1")
+	if err != nil || string(e.Value()) != `{"ID":1,"Name":"a"}` {
+		t.Fatalf("unexpected raw entry: %v, err: %v", e, err)
+	}
+	u, err := c.Get(ctx, "u1")
+	if err != nil || u.Value() != (user{ID: 1, Name: "a"}) {
+		t.Fatalf("unexpected entry: %v, err: %v", u, err)
+	}
+	_ = raw.Set(ctx, cache.NewEntry("bad", []byte("{"), 0))
+	if _, err = c.Get(ctx, "bad"); err == nil {
+		t.Fatalf("unexpected success decoding bad value")
+	}
+	entries, err := c.MGet(ctx, []string{"u1", "u2"})
+	if err != nil || entries[0].Value().ID != 1 || entries[1] != nil {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	_ = c.Del(ctx, "u1")
+	if _, err = c.Get(ctx, "u1"); err == nil {
+		t.Fatalf("unexpected hit after del")
+	}
+}
+
+func TestCodecCacher_Plain(t *testing.T) {
+	raw := memory.New[[]byte]()
+	c := cache.NewCodecCacher[user](raw, codec.JSON[user]{})
+	_ = c.Set(ctx, cache.NewEntryWithMetadata("u1", user{ID: 1}, 0, cache.Metadata{Version: 1}))
+	// only the value is encoded, the metadata is kept by the bytes cacher
+	if e, err := raw.Get(ctx, "u1"); err != nil || string(e.Value()) != `{"ID":1,"Name":""}` {
+		t.Fatalf("unexpected raw entry: %v, err: %v", e, err)
+	}
+	e, err := c.Get(ctx, "u1")
+	if err != nil || e.Value().ID != 1 || cache.MetadataOf(e).Version != 1 || !cache.KeepsMetadata(c) {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+}
+
+func TestCodecCacher_Legacy(t *testing.T) {
+	raw := memory.New[[]byte]()
+	_ = raw.Set(ctx,
+		cache.NewEntry("u1", []byte(`{"ID":1}`), 0),
+		cache.NewEntry("bad", []byte("\xffac\x01\xff"), 0),
+	)
+	c := cache.NewCodecCacher[user](raw, codec.JSON[user]{}, cache.WithMetadata())
+	_ = c.Set(ctx, cache.NewEntryWithMetadata("u2", user{ID: 2}, 0, cache.Metadata{Version: 1}))
+	entries, err := c.MGet(ctx, []string{"u1", "u2", "bad"})
+	if err != nil || entries[0].Value().ID != 1 || entries[1].Value().ID != 2 || entries[2] != nil {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	if md := cache.MetadataOf(entries[1]); md.Version != 1 {
+		t.Fatalf("metadata not preserved: %+v", md)
+	}
+	// malformed entries are misses
+	if _, err = c.Get(ctx, "bad"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	b := cache.NewCodecCacher[[]byte](memory.New[[]byte](), codec.Bytes{}, cache.WithMetadata())
+	_ = b.Set(ctx, cache.NewEntry("b", []byte("\xffac\x01\xff"), 0))
+	if e, err := b.Get(ctx, "b"); err != nil || string(e.Value()) != "\xffac\x01\xff" {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+}
+
+func TestCodecCacher_Metadata(t *testing.T) {
+	// the bytes cacher drops metadata, it is read back from the encoded entries
+	c := cache.NewCodecCacher[user](dropMetadata(memory.New[[]byte]()), codec.JSON[user]{}, cache.WithMetadata())
+	soft := time.Unix(1700000000, 123)
+	_ = c.Set(ctx,
+		cache.NewEntryWithMetadata("u1", user{}, 0, cache.Metadata{NotFound: true}),
+		cache.NewEntryWithMetadata("u2", user{ID: 2}, 0, cache.Metadata{
+			SoftExpiresAt: soft,
+			ExpiresAt:     soft.Add(time.Second),
+			StoredAt:      soft.Add(-time.Second),
+			Version:       42,
+			Origin:        cache.OriginRefresh,
+		}),
+	)
+	entries, err := c.MGet(ctx, []string{"u1", "u2"})
+	if err != nil || !cache.MetadataOf(entries[0]).NotFound {
+		t.Fatalf("metadata not preserved: %v, err: %v", entries, err)
+	}
+	md := cache.MetadataOf(entries[1])
+	if md.NotFound || !md.SoftExpiresAt.Equal(soft) || !md.ExpiresAt.Equal(soft.Add(time.Second)) || entries[1].Value().ID != 2 {
+		t.Fatalf("metadata not preserved: %+v, value: %v", md, entries[1].Value())
+	}
+	if !md.StoredAt.Equal(soft.Add(-time.Second)) || md.Version != 42 || md.Origin != cache.OriginRefresh {
+		t.Fatalf("metadata not preserved: %+v, value: %v", md, entries[1].Value())
+	}
+}
+
+// dropMetadata makes a Cacher of raw bytes return entries without metadata.
+func dropMetadata(c cache.Cacher[[]byte]) cache.Cacher[[]byte] {
+	return metadataDropper{c}
+}
+
+type metadataDropper struct {
+	cache.Cacher[[]byte]
+}
+
+func (d metadataDropper) MGet(ctx context.Context, keys []string) ([]cache.Entry[[]byte], error) {
+	entries, err := d.Cacher.MGet(ctx, keys)
+	for i, e := range entries {
+		if e != nil {
+			entries[i] = cache.NewEntry(e.Key(), e.Value(), e.Expiration())
+		}
+	}
+	return entries, err
+}
@@ -0,0 +1,187 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+// Algorithm is written as the first byte of every stored value.
+type Algorithm byte
+
+const (
+	None Algorithm = iota
+	Gzip
+	Flate
+)
+
+var ErrCorrupt = errors.New("compress: corrupt value")
+
+type Option func(o *options)
+
+type options struct {
+	threshold int
+	algorithm Algorithm
+	level     int
+}
+
+// WithThreshold sets the value size from which values are compressed.
+func WithThreshold(threshold int) Option {
+	return func(o *options) {
+		o.threshold = threshold
+	}
+}
+
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = algorithm
+	}
+}
+
+// WithLevel sets the compression level, see compress/flate.
+func WithLevel(level int) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+type compressCacher struct {
+	cacher cache.Cacher[[]byte]
+	options
+}
+
+// New wraps cacher so that values of at least the threshold size are stored
+// compressed. Values written with any algorithm are readable regardless of
+// the configured one.
+func New(cacher cache.Cacher[[]byte], opts ...Option) cache.Cacher[[]byte] {
+	if cacher == nil {
+		panic("nil cache")
+	}
+	c := &compressCacher{
+		cacher: cacher,
+		options: options{
+			threshold: 1024,
+			algorithm: Gzip,
+			level:     flate.DefaultCompression,
+		},
+	}
+	for _, opt := range opts {
+		opt(&c.options)
+	}
+	if c.algorithm > Flate {
+		panic(fmt.Sprintf("unknown algorithm %d", c.algorithm))
+	}
+	if c.level < flate.HuffmanOnly || c.level > flate.BestCompression {
+		panic(fmt.Sprintf("invalid level %d", c.level))
+	}
+	return c
+}
+
+func (c *compressCacher) Get(ctx context.Context, key string) (cache.Entry[[]byte], error) {
+	e, err := c.cacher.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decompressEntry(e)
+}
+
+func (c *compressCacher) MGet(ctx context.Context, keys []string) ([]cache.Entry[[]byte], error) {
+	entries, err := c.cacher.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+		if entries[i], err = decompressEntry(e); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (c *compressCacher) Set(ctx context.Context, entries ...cache.Entry[[]byte]) error {
+	compressed := make([]cache.Entry[[]byte], 0, len(entries))
+	for _, e := range entries {
+		data, err := c.compress(e.Value())
+		if err != nil {
+			return err
+		}
+		compressed = append(compressed, cache.NewEntryWithMetadata(e.Key(), data, e.Expiration(), cache.MetadataOf(e)))
+	}
+	return c.cacher.Set(ctx, compressed...)
+}
+
+func (c *compressCacher) Del(ctx context.Context, keys ...string) error {
+	return c.cacher.Del(ctx, keys...)
+}
+
+func (c *compressCacher) KeepsMetadata() bool {
+	return cache.KeepsMetadata(c.cacher)
+}
+
+// compress falls back to storing value as is when it is below the threshold
+// or does not shrink.
+func (c *compressCacher) compress(value []byte) ([]byte, error) {
+	if len(value) < c.threshold || c.algorithm == None {
+		return append([]byte{byte(None)}, value...), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(byte(c.algorithm))
+	var w io.WriteCloser
+	var err error
+	switch c.algorithm {
+	case Gzip:
+		w, err = gzip.NewWriterLevel(&buf, c.level)
+	case Flate:
+		w, err = flate.NewWriter(&buf, c.level)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(value); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() > len(value) {
+		return append([]byte{byte(None)}, value...), nil
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressEntry(e cache.Entry[[]byte]) (cache.Entry[[]byte], error) {
+	data := e.Value()
+	if len(data) == 0 {
+		return nil, ErrCorrupt
+	}
+	var r io.ReadCloser
+	switch Algorithm(data[0]) {
+	case None:
+		return cache.NewEntryWithMetadata(e.Key(), data[1:], e.Expiration(), cache.MetadataOf(e)), nil
+	case Gzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+		}
+		r = gr
+	case Flate:
+		r = flate.NewReader(bytes.NewReader(data[1:]))
+	default:
+		return nil, fmt.Errorf("%w: unknown algorithm %d", ErrCorrupt, data[0])
+	}
+	defer r.Close()
+	value, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	return cache.NewEntryWithMetadata(e.Key(), value, e.Expiration(), cache.MetadataOf(e)), nil
+}
@@ -0,0 +1,50 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+var ctx = context.Background()
+
+func TestCompress(t *testing.T) {
+	large := []byte(strings.Repeat(`{"name":"anycache"}`, 100))
+	for _, algorithm := range []Algorithm{None, Gzip, Flate} {
+		raw := memory.New[[]byte]()
+		c := New(raw, WithAlgorithm(algorithm), WithThreshold(64))
+		_ = c.Set(ctx, cache.NewEntry("small", []byte("abc"), 0), cache.NewEntry("large", large, 0))
+
+		stored, _ := raw.MGet(ctx, []string{"small", "large"})
+		if !bytes.Equal(stored[0].Value(), []byte("\x00abc")) {
+			t.Fatalf("small value compressed: %q", stored[0].Value())
+		}
+		if Algorithm(stored[1].Value()[0]) != algorithm || (algorithm != None && len(stored[1].Value()) >= len(large)) {
+			t.Fatalf("large value not compressed with %d, len: %d", algorithm, len(stored[1].Value()))
+		}
+
+		entries, err := c.MGet(ctx, []string{"small", "missing", "large"})
+		if err != nil || string(entries[0].Value()) != "abc" || entries[1] != nil || !bytes.Equal(entries[2].Value(), large) {
+			t.Fatalf("unexpected entries with %d: %v, err: %v", algorithm, entries, err)
+		}
+		e, err := c.Get(ctx, "large")
+		if err != nil || !bytes.Equal(e.Value(), large) {
+			t.Fatalf("unexpected entry with %d, err: %v", algorithm, err)
+		}
+	}
+}
+
+func TestCompress_Corrupt(t *testing.T) {
+	raw := memory.New[[]byte]()
+	c := New(raw)
+	_ = raw.Set(ctx, cache.NewEntry("empty", []byte{}, 0), cache.NewEntry("bad", []byte{byte(Gzip), 1, 2}, 0))
+	for _, key := range []string{"empty", "bad"} {
+		if _, err := c.Get(ctx, key); err == nil {
+			t.Fatalf("unexpected success reading %s", key)
+		}
+	}
+}
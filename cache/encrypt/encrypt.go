@@ -0,0 +1,117 @@
+package encrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+type encryptCacher struct {
+	cacher   cache.Cacher[[]byte]
+	aeads    map[byte]cipher.AEAD
+	activeID byte
+}
+
+// New wraps cacher so that every value is sealed with AES-GCM, using the
+// cache key as additional authenticated data so a value copied to another key
+// fails to open.
+//
+// keys maps a key ID to a 16, 24 or 32 bytes AES key. Values are sealed with
+// activeID, whose ID is stored as the first byte of the value, and opened with
+// whichever key they were sealed with, so rotating only requires adding the
+// new key and switching activeID while the old one is still listed.
+//
+// Values that fail to open, because they were tampered with or their key ID is
+// unknown, are reported as misses.
+func New(cacher cache.Cacher[[]byte], keys map[byte][]byte, activeID byte) (cache.Cacher[[]byte], error) {
+	if cacher == nil {
+		panic("nil cache")
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("encrypt: active key %d not found", activeID)
+	}
+	aeads := make(map[byte]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: key %d: %w", id, err)
+		}
+		if aeads[id], err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("encrypt: key %d: %w", id, err)
+		}
+	}
+	return &encryptCacher{
+		cacher:   cacher,
+		aeads:    aeads,
+		activeID: activeID,
+	}, nil
+}
+
+func (c *encryptCacher) Get(ctx context.Context, key string) (cache.Entry[[]byte], error) {
+	e, err := c.cacher.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if e = c.open(e); e == nil {
+		return nil, cache.ErrNotFound
+	}
+	return e, nil
+}
+
+func (c *encryptCacher) MGet(ctx context.Context, keys []string) ([]cache.Entry[[]byte], error) {
+	entries, err := c.cacher.MGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if e != nil {
+			entries[i] = c.open(e)
+		}
+	}
+	return entries, nil
+}
+
+func (c *encryptCacher) Set(ctx context.Context, entries ...cache.Entry[[]byte]) error {
+	aead := c.aeads[c.activeID]
+	sealed := make([]cache.Entry[[]byte], 0, len(entries))
+	for _, e := range entries {
+		data := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(e.Value())+aead.Overhead())
+		data[0] = c.activeID
+		if _, err := rand.Read(data[1:]); err != nil {
+			return err
+		}
+		data = aead.Seal(data, data[1:], e.Value(), []byte(e.Key()))
+		sealed = append(sealed, cache.NewEntryWithMetadata(e.Key(), data, e.Expiration(), cache.MetadataOf(e)))
+	}
+	return c.cacher.Set(ctx, sealed...)
+}
+
+func (c *encryptCacher) Del(ctx context.Context, keys ...string) error {
+	return c.cacher.Del(ctx, keys...)
+}
+
+func (c *encryptCacher) KeepsMetadata() bool {
+	return cache.KeepsMetadata(c.cacher)
+}
+
+// open returns nil when e cannot be authenticated.
+func (c *encryptCacher) open(e cache.Entry[[]byte]) cache.Entry[[]byte] {
+	data := e.Value()
+	if len(data) == 0 {
+		return nil
+	}
+	aead, ok := c.aeads[data[0]]
+	if !ok || len(data) < 1+aead.NonceSize()+aead.Overhead() {
+		return nil
+	}
+	nonce, ciphertext := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+	value, err := aead.Open(nil, nonce, ciphertext, []byte(e.Key()))
+	if err != nil {
+		return nil
+	}
+	return cache.NewEntryWithMetadata(e.Key(), value, e.Expiration(), cache.MetadataOf(e))
+}
@@ -0,0 +1,76 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+var ctx = context.Background()
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func TestEncrypt(t *testing.T) {
+	raw := memory.New[[]byte]()
+	c, err := New(raw, map[byte][]byte{1: key1}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Set(ctx, cache.NewEntry("a", []byte("secret"), 0), cache.NewEntry("b", []byte("other"), 0))
+	stored, _ := raw.Get(ctx, "a")
+	if stored.Value()[0] != 1 || bytes.Contains(stored.Value(), []byte("secret")) {
+		t.Fatalf("value not encrypted: %q", stored.Value())
+	}
+	entries, err := c.MGet(ctx, []string{"a", "missing", "b"})
+	if err != nil || string(entries[0].Value()) != "secret" || entries[1] != nil || string(entries[2].Value()) != "other" {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+
+	// swapped between keys
+	_ = raw.Set(ctx, cache.NewEntry("b", stored.Value(), 0))
+	if _, err = c.Get(ctx, "b"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("swapped value accepted: %v", err)
+	}
+	// tampered
+	tampered := bytes.Clone(stored.Value())
+	tampered[len(tampered)-1] ^= 1
+	_ = raw.Set(ctx, cache.NewEntry("a", tampered, 0))
+	if entries, _ = c.MGet(ctx, []string{"a"}); entries[0] != nil {
+		t.Fatalf("tampered value accepted")
+	}
+}
+
+func TestEncrypt_Rotation(t *testing.T) {
+	raw := memory.New[[]byte]()
+	old, _ := New(raw, map[byte][]byte{1: key1}, 1)
+	_ = old.Set(ctx, cache.NewEntry("a", []byte("v1"), 0))
+
+	rotated, err := New(raw, map[byte][]byte{1: key1, 2: key2}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := rotated.Get(ctx, "a"); err != nil || string(e.Value()) != "v1" {
+		t.Fatalf("value sealed with old key not readable: %v", err)
+	}
+	_ = rotated.Set(ctx, cache.NewEntry("a", []byte("v2"), 0))
+	if stored, _ := raw.Get(ctx, "a"); stored.Value()[0] != 2 {
+		t.Fatalf("value not sealed with active key")
+	}
+	if _, err = old.Get(ctx, "a"); err == nil {
+		t.Fatalf("value with unknown key id accepted")
+	}
+
+	if _, err = New(raw, map[byte][]byte{1: key1}, 2); err == nil {
+		t.Fatalf("missing active key accepted")
+	}
+	if _, err = New(raw, map[byte][]byte{1: []byte("short")}, 1); err == nil {
+		t.Fatalf("invalid key accepted")
+	}
+}
@@ -8,10 +8,57 @@ type Entry[V any] interface {
 	Expiration() time.Duration
 }
 
+// Metadata is optional information attached to an entry. Backends keeping the
+// entries they are given, or encoding them with MarshalEntry, preserve it.
+type Metadata struct {
+	// NotFound marks a tombstone recording that the source has no value.
+	NotFound bool
+	// SoftExpiresAt is when the value becomes stale and should be reloaded,
+	// while still being served until the entry expires.
+	SoftExpiresAt time.Time
+	// ExpiresAt is when the value expires, zero if it never does.
+	ExpiresAt time.Time
+	// StoredAt is when the value was produced.
+	StoredAt time.Time
+	// Version increases with every value produced by a process. Versions from
+	// different processes or hosts derive from their wall clocks and are not
+	// ordered reliably.
+	Version uint64
+	// Origin is what produced the value.
+	Origin Origin
+}
+
+// Origin identifies what produced a cached value.
+type Origin uint8
+
+const (
+	OriginUnknown Origin = iota
+	// OriginLoader marks a value loaded on a miss.
+	OriginLoader
+	// OriginSet marks a value stored by Set or MSet.
+	OriginSet
+	// OriginRefresh marks a value reloaded by Refresh or in the background.
+	OriginRefresh
+)
+
+func (o Origin) String() string {
+	switch o {
+	case OriginLoader:
+		return "loader"
+	case OriginSet:
+		return "set"
+	case OriginRefresh:
+		return "refresh"
+	default:
+		return "unknown"
+	}
+}
+
 type entry[V any] struct {
 	key        string
 	value      V
 	expiration time.Duration
+	metadata   Metadata
 }
 
 func NewEntry[V any](key string, value V, expiration time.Duration) Entry[V] {
@@ -22,6 +69,23 @@ func NewEntry[V any](key string, value V, expiration time.Duration) Entry[V] {
 	}
 }
 
+func NewEntryWithMetadata[V any](key string, value V, expiration time.Duration, metadata Metadata) Entry[V] {
+	return &entry[V]{
+		key:        key,
+		value:      value,
+		expiration: expiration,
+		metadata:   metadata,
+	}
+}
+
+// MetadataOf returns the metadata of e, or the zero Metadata if e has none.
+func MetadataOf[V any](e Entry[V]) Metadata {
+	if m, ok := e.(interface{ Metadata() Metadata }); ok {
+		return m.Metadata()
+	}
+	return Metadata{}
+}
+
 func (e *entry[V]) Key() string {
 	return e.key
 }
@@ -33,3 +97,7 @@ func (e *entry[V]) Value() V {
 func (e *entry[V]) Expiration() time.Duration {
 	return e.expiration
 }
+
+func (e *entry[V]) Metadata() Metadata {
+	return e.metadata
+}
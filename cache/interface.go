@@ -1,6 +1,13 @@
 package cache
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by Cacher.Get when the key is not cached, any other
+// error is a failure of the backend. MGet reports misses as nil entries.
+var ErrNotFound = errors.New("cache: not found")
 
 type Cacher[V any] interface {
 	Get(ctx context.Context, key string) (Entry[V], error)
@@ -8,3 +15,16 @@ type Cacher[V any] interface {
 	Set(ctx context.Context, entry ...Entry[V]) error
 	Del(ctx context.Context, key ...string) error
 }
+
+// MetadataKeeper is implemented by Cachers declaring whether the entries they
+// return carry the Metadata they were stored with. Features relying on
+// metadata, like tombstones, refuse Cachers that do not declare it.
+type MetadataKeeper interface {
+	KeepsMetadata() bool
+}
+
+// KeepsMetadata reports whether c declares to preserve entry metadata.
+func KeepsMetadata[V any](c Cacher[V]) bool {
+	k, ok := c.(MetadataKeeper)
+	return ok && k.KeepsMetadata()
+}
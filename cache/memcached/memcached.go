@@ -0,0 +1,350 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/codec"
+	"github.com/xianlianghe0123/anycache/internal/pool"
+)
+
+const (
+	maxKeyLength = 250
+	// relative expiration times above 30 days are read as unix timestamps
+	maxRelativeExpiration = 30 * 24 * time.Hour
+)
+
+var ErrKey = errors.New("memcached: invalid key")
+
+// ServerError is an error or unexpected status reply sent by the server.
+type ServerError string
+
+func (e ServerError) Error() string {
+	return "memcached: " + string(e)
+}
+
+type Option func(o *options)
+
+type options struct {
+	poolSize    int
+	dialTimeout time.Duration
+	ioTimeout   time.Duration
+	metadata    bool
+}
+
+func WithPoolSize(size int) Option {
+	return func(o *options) {
+		o.poolSize = size
+	}
+}
+
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithIOTimeout bounds every round trip whose context has no deadline.
+func WithIOTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.ioTimeout = timeout
+	}
+}
+
+// WithMetadata stores the entry metadata along with the values, see
+// cache.MarshalEntry. By default values are stored as the bare codec payload,
+// readable by any client using the same codec, and metadata is dropped.
+func WithMetadata() Option {
+	return func(o *options) {
+		o.metadata = true
+	}
+}
+
+// Cache is a cache.Cacher storing codec encoded values in memcached using
+// the ASCII protocol.
+type Cache[V any] struct {
+	pool     *pool.Pool
+	codec    codec.Codec[V]
+	metadata bool
+}
+
+var _ cache.Cacher[any] = (*Cache[any])(nil)
+
+func New[V any](addr string, c codec.Codec[V], opts ...Option) *Cache[V] {
+	if c == nil {
+		panic("codec is nil")
+	}
+	o := &options{
+		poolSize:    10,
+		dialTimeout: 5 * time.Second,
+		ioTimeout:   3 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Cache[V]{
+		pool: pool.New(pool.Config{
+			Addr:        addr,
+			Size:        o.poolSize,
+			DialTimeout: o.dialTimeout,
+			IOTimeout:   o.ioTimeout,
+		}),
+		codec:    c,
+		metadata: o.metadata,
+	}
+}
+
+// ValidKey reports whether key is accepted by memcached: at most 250 bytes
+// without whitespace or control characters.
+func ValidKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cache[V]) Get(ctx context.Context, key string) (cache.Entry[V], error) {
+	entries, err := c.MGet(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	if entries[0] == nil {
+		return nil, cache.ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// MGet fetches all keys with a single multi-key get command.
+func (c *Cache[V]) MGet(ctx context.Context, keys []string) ([]cache.Entry[V], error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if err := validateKeys(keys); err != nil {
+		return nil, err
+	}
+	var values map[string][]byte
+	err := c.do(ctx, func(conn *pool.Conn) error {
+		conn.W.WriteString("get")
+		for _, key := range keys {
+			conn.W.WriteByte(' ')
+			conn.W.WriteString(key)
+		}
+		conn.W.WriteString("\r\n")
+		if err := conn.W.Flush(); err != nil {
+			return err
+		}
+		var err error
+		values, err = readValues(conn.R)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]cache.Entry[V], len(keys))
+	for i, key := range keys {
+		data, ok := values[key]
+		if !ok {
+			continue
+		}
+		entries[i], err = c.unmarshal(key, data)
+		if errors.Is(err, cache.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Set pipelines one set command per entry.
+func (c *Cache[V]) Set(ctx context.Context, entries ...cache.Entry[V]) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	payloads := make([][]byte, len(entries))
+	for i, e := range entries {
+		if !ValidKey(e.Key()) {
+			return fmt.Errorf("%w: %q", ErrKey, e.Key())
+		}
+		data, err := c.marshal(e)
+		if err != nil {
+			return err
+		}
+		payloads[i] = data
+	}
+	now := time.Now()
+	return c.do(ctx, func(conn *pool.Conn) error {
+		for i, e := range entries {
+			fmt.Fprintf(conn.W, "set %s 0 %d %d\r\n", e.Key(), exptime(e.Expiration(), now), len(payloads[i]))
+			conn.W.Write(payloads[i])
+			conn.W.WriteString("\r\n")
+		}
+		if err := conn.W.Flush(); err != nil {
+			return err
+		}
+		return readStatuses(conn.R, len(entries), "STORED")
+	})
+}
+
+func (c *Cache[V]) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := validateKeys(keys); err != nil {
+		return err
+	}
+	return c.do(ctx, func(conn *pool.Conn) error {
+		for _, key := range keys {
+			conn.W.WriteString("delete " + key + "\r\n")
+		}
+		if err := conn.W.Flush(); err != nil {
+			return err
+		}
+		return readStatuses(conn.R, len(keys), "DELETED", "NOT_FOUND")
+	})
+}
+
+// KeepsMetadata reports whether the cache was created WithMetadata.
+func (c *Cache[V]) KeepsMetadata() bool {
+	return c.metadata
+}
+func (c *Cache[V]) marshal(e cache.Entry[V]) ([]byte, error) {
+	if c.metadata {
+		return cache.MarshalEntry(e, c.codec)
+	}
+	return c.codec.Marshal(e.Value())
+}
+
+func (c *Cache[V]) unmarshal(key string, data []byte) (cache.Entry[V], error) {
+	if c.metadata {
+		return cache.UnmarshalEntry(key, data, 0, c.codec)
+	}
+	value, err := c.codec.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewEntry(key, value, 0), nil
+}
+
+func (c *Cache[V]) Close() error {
+	return c.pool.Close()
+}
+
+func (c *Cache[V]) do(ctx context.Context, fn func(conn *pool.Conn) error) error {
+	conn, err := c.pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	var serverErr ServerError
+	if errors.As(err, &serverErr) {
+		c.pool.Put(conn, nil)
+	} else {
+		c.pool.Put(conn, err)
+	}
+	return err
+}
+
+func validateKeys(keys []string) error {
+	for _, key := range keys {
+		if !ValidKey(key) {
+			return fmt.Errorf("%w: %q", ErrKey, key)
+		}
+	}
+	return nil
+}
+
+// exptime converts a ttl to memcached exptime, rounding sub-second ttls up so
+// they do not become 0 which means never expire.
+func exptime(ttl time.Duration, now time.Time) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > maxRelativeExpiration {
+		return now.Add(ttl).Unix()
+	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+func readValues(r *bufio.Reader) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if string(line) == "END" {
+			return values, nil
+		}
+		fields := bytes.Fields(line)
+		if len(fields) < 4 || string(fields[0]) != "VALUE" {
+			if isErrorReply(line) {
+				return nil, ServerError(line)
+			}
+			return nil, fmt.Errorf("memcached: malformed reply %q", line)
+		}
+		n, err := strconv.Atoi(string(fields[3]))
+		if err != nil {
+			return nil, fmt.Errorf("memcached: malformed reply %q", line)
+		}
+		key := string(fields[1])
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		values[key] = data[:n]
+	}
+}
+
+// readStatuses reads n single line replies, returning the first unexpected
+// one as an error once all of them are consumed.
+func readStatuses(r *bufio.Reader, n int, expected ...string) error {
+	var firstErr error
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		ok := false
+		for _, status := range expected {
+			if string(line) == status {
+				ok = true
+				break
+			}
+		}
+		if !ok && firstErr == nil {
+			firstErr = ServerError(line)
+		}
+	}
+	return firstErr
+}
+
+func isErrorReply(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("ERROR")) ||
+		bytes.HasPrefix(line, []byte("CLIENT_ERROR")) ||
+		bytes.HasPrefix(line, []byte("SERVER_ERROR"))
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("memcached: malformed reply %q", line)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,173 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/codec"
+)
+
+var ctx = context.Background()
+
+type fakeServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	data     map[string]string
+	exptimes map[string]int64
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, data: map[string]string{}, exptimes: map[string]int64{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		s.mu.Lock()
+		switch fields[0] {
+		case "get":
+			for _, key := range fields[1:] {
+				if v, ok := s.data[key]; ok {
+					w.WriteString("VALUE " + key + " 0 " + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+				}
+			}
+			w.WriteString("END\r\n")
+		case "set":
+			n, _ := strconv.Atoi(fields[4])
+			data := make([]byte, n+2)
+			if _, err = io.ReadFull(r, data); err != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.data[fields[1]] = string(data[:n])
+			s.exptimes[fields[1]], _ = strconv.ParseInt(fields[3], 10, 64)
+			w.WriteString("STORED\r\n")
+		case "delete":
+			if _, ok := s.data[fields[1]]; ok {
+				delete(s.data, fields[1])
+				w.WriteString("DELETED\r\n")
+			} else {
+				w.WriteString("NOT_FOUND\r\n")
+			}
+		default:
+			w.WriteString("ERROR\r\n")
+		}
+		s.mu.Unlock()
+		if r.Buffered() == 0 {
+			_ = w.Flush()
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	s := newFakeServer(t)
+	c := New[string](s.ln.Addr().String(), codec.String{})
+	defer c.Close()
+
+	if _, err := c.Get(ctx, "a"); err == nil {
+		t.Fatalf("unexpected hit")
+	}
+	err := c.Set(ctx,
+		cache.NewEntry("a", "1", 0),
+		cache.NewEntry("b", "2\r\n", 1500*time.Millisecond),
+		cache.NewEntry("c", "3", 31*24*time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	s.mu.Lock()
+	exptimes := [3]int64{s.exptimes["a"], s.exptimes["b"], s.exptimes["c"]}
+	s.mu.Unlock()
+	if exptimes[0] != 0 || exptimes[1] != 2 || exptimes[2] < time.Now().Unix() {
+		t.Fatalf("unexpected exptimes: %v", exptimes)
+	}
+	e, err := c.Get(ctx, "a")
+	if err != nil || e.Value() != "1" {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+	entries, err := c.MGet(ctx, []string{"a", "d", "b"})
+	if err != nil || entries[0].Value() != "1" || entries[1] != nil || entries[2].Value() != "2\r\n" {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	if err = c.Del(ctx, "a", "d"); err != nil {
+		t.Fatalf("del failed: %v", err)
+	}
+	if _, err = c.Get(ctx, "a"); err == nil {
+		t.Fatalf("unexpected hit after del")
+	}
+}
+
+func TestCache_Metadata(t *testing.T) {
+	s := newFakeServer(t)
+	md := cache.Metadata{StoredAt: time.Unix(1700000000, 0), Version: 7, Origin: cache.OriginLoader}
+	// metadata is dropped by default, the value being stored alone
+	c := New[string](s.ln.Addr().String(), codec.String{})
+	defer c.Close()
+	_ = c.Set(ctx, cache.NewEntryWithMetadata("a", "1", 0, md))
+	if e, err := c.Get(ctx, "a"); err != nil || e.Value() != "1" || cache.MetadataOf(e) != (cache.Metadata{}) || cache.KeepsMetadata[string](c) {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+
+	m := New[string](s.ln.Addr().String(), codec.String{}, WithMetadata())
+	defer m.Close()
+	_ = m.Set(ctx, cache.NewEntryWithMetadata("b", "2", 0, md), cache.NewEntryWithMetadata("c", "", 0, cache.Metadata{NotFound: true}))
+	entries, err := m.MGet(ctx, []string{"a", "b", "c"})
+	if err != nil || entries[0].Value() != "1" || entries[1].Value() != "2" || !cache.KeepsMetadata[string](m) {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	if got := cache.MetadataOf(entries[1]); !got.StoredAt.Equal(md.StoredAt) || got.Version != 7 || got.Origin != cache.OriginLoader {
+		t.Fatalf("metadata not preserved: %+v", got)
+	}
+	if !cache.MetadataOf(entries[2]).NotFound {
+		t.Fatalf("tombstone not preserved: %v", entries[2])
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	for key, valid := range map[string]bool{
+		"user:1":                 true,
+		"":                       false,
+		"with space":             false,
+		"new\nline":              false,
+		strings.Repeat("k", 250): true,
+		strings.Repeat("k", 251): false,
+		"\x7f":                   false,
+	} {
+		if ValidKey(key) != valid {
+			t.Fatalf("ValidKey(%q) != %v", key, valid)
+		}
+	}
+	c := New[string]("127.0.0.1:0", codec.String{})
+	if err := c.Set(ctx, cache.NewEntry("bad key", "v", 0)); err == nil {
+		t.Fatalf("invalid key accepted")
+	}
+}
@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+const defaultShards = 16
+
+// sweepSteps is the number of entries every set checks for expiration, so
+// that entries never read again are removed as well.
+const sweepSteps = 2
+
+type Option func(o *options)
+
+type options struct {
+	shards     int
+	maxEntries int
+}
+
+// WithShards sets the number of lock stripes, rounded up to a power of two.
+func WithShards(shards int) Option {
+	return func(o *options) {
+		o.shards = shards
+	}
+}
+
+// WithMaxEntries bounds the number of entries kept, 0 means unbounded.
+// The bound is split across the shards, which evict on their own: the cache
+// never holds more than maxEntries entries, but a shard may start evicting
+// before the whole cache is full. The number of shards is lowered to fit
+// maxEntries when it is smaller.
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// Cache is a sharded LRU implementing cache.Cacher, safe for concurrent use.
+type Cache[V any] struct {
+	shards []*shard[V]
+	mask   uint32
+}
+
+var _ cache.Cacher[any] = (*Cache[any])(nil)
+
+func New[V any](opts ...Option) *Cache[V] {
+	o := &options{shards: defaultShards}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.shards <= 0 {
+		panic("shards must be positive")
+	}
+	if o.maxEntries < 0 {
+		panic("maxEntries must not be negative")
+	}
+	n := 1
+	for n < o.shards {
+		n <<= 1
+	}
+	for o.maxEntries > 0 && n > o.maxEntries {
+		n >>= 1
+	}
+	c := &Cache[V]{
+		shards: make([]*shard[V], n),
+		mask:   uint32(n - 1),
+	}
+	for i := range c.shards {
+		// the first maxEntries%n shards take one extra entry so that the
+		// capacities add up to maxEntries exactly.
+		capacity := 0
+		if o.maxEntries > 0 {
+			capacity = o.maxEntries / n
+			if i < o.maxEntries%n {
+				capacity++
+			}
+		}
+		c.shards[i] = newShard[V](capacity)
+	}
+	return c
+}
+
+func (c *Cache[V]) Get(ctx context.Context, key string) (cache.Entry[V], error) {
+	e, ok := c.shard(key).get(key, time.Now())
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	return e, nil
+}
+
+func (c *Cache[V]) MGet(ctx context.Context, keys []string) ([]cache.Entry[V], error) {
+	now := time.Now()
+	entries := make([]cache.Entry[V], len(keys))
+	for i, key := range keys {
+		if e, ok := c.shard(key).get(key, now); ok {
+			entries[i] = e
+		}
+	}
+	return entries, nil
+}
+
+func (c *Cache[V]) Set(ctx context.Context, entries ...cache.Entry[V]) error {
+	now := time.Now()
+	for _, e := range entries {
+		c.shard(e.Key()).set(e, now)
+	}
+	return nil
+}
+
+func (c *Cache[V]) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.shard(key).del(key)
+	}
+	return nil
+}
+
+// KeepsMetadata returns true, entries are kept as they are given.
+func (c *Cache[V]) KeepsMetadata() bool {
+	return true
+}
+
+// Len returns the number of stored entries, including expired ones not yet
+// removed. Expired entries are removed when read, and swept a few at a time by
+// every Set.
+func (c *Cache[V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.ll.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (c *Cache[V]) shard(key string) *shard[V] {
+	// inline fnv-1a to avoid allocating a hash.Hash per call
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return c.shards[h&c.mask]
+}
+
+type item[V any] struct {
+	entry    cache.Entry[V]
+	expireAt time.Time
+}
+
+func (it *item[V]) expired(now time.Time) bool {
+	return !it.expireAt.IsZero() && !now.Before(it.expireAt)
+}
+
+type shard[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	ll       *list.List
+	// cursor is the next entry checked by sweep, which walks the list from
+	// the back to the front.
+	cursor *list.Element
+}
+
+func newShard[V any](capacity int) *shard[V] {
+	return &shard[V]{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		ll:       list.New(),
+	}
+}
+
+func (s *shard[V]) get(key string, now time.Time) (cache.Entry[V], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	it := elem.Value.(*item[V])
+	if it.expired(now) {
+		s.remove(elem)
+		return nil, false
+	}
+	s.moveToFront(elem)
+	return it.entry, true
+}
+
+func (s *shard[V]) set(e cache.Entry[V], now time.Time) {
+	it := &item[V]{entry: e}
+	if e.Expiration() > 0 {
+		it.expireAt = now.Add(e.Expiration())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	if elem, ok := s.items[e.Key()]; ok {
+		elem.Value = it
+		s.moveToFront(elem)
+		return
+	}
+	s.items[e.Key()] = s.ll.PushFront(it)
+	if s.capacity > 0 && s.ll.Len() > s.capacity {
+		s.remove(s.ll.Back())
+	}
+}
+
+// sweep removes the expired entries among the next sweepSteps ones of the
+// cursor, starting over from the back once it reaches the front.
+func (s *shard[V]) sweep(now time.Time) {
+	for i := 0; i < sweepSteps && s.ll.Len() > 0; i++ {
+		if s.cursor == nil {
+			s.cursor = s.ll.Back()
+		}
+		elem := s.cursor
+		s.cursor = elem.Prev()
+		if elem.Value.(*item[V]).expired(now) {
+			s.remove(elem)
+		}
+	}
+}
+
+func (s *shard[V]) moveToFront(elem *list.Element) {
+	if s.cursor == elem {
+		s.cursor = elem.Prev()
+	}
+	s.ll.MoveToFront(elem)
+}
+
+func (s *shard[V]) del(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.items[key]; ok {
+		s.remove(elem)
+	}
+}
+
+func (s *shard[V]) remove(elem *list.Element) {
+	if s.cursor == elem {
+		s.cursor = elem.Prev()
+	}
+	s.ll.Remove(elem)
+	delete(s.items, elem.Value.(*item[V]).entry.Key())
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+var ctx = context.Background()
+
+func TestCache_GetSetDel(t *testing.T) {
+	c := New[string]()
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	_ = c.Set(ctx, cache.NewEntry("a", "1", 0), cache.NewEntry("b", "2", 0))
+	e, err := c.Get(ctx, "a")
+	if err != nil || e.Value() != "1" {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+	entries, _ := c.MGet(ctx, []string{"a", "c", "b"})
+	if entries[0].Value() != "1" || entries[1] != nil || entries[2].Value() != "2" {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+	_ = c.Del(ctx, "a", "c")
+	if _, err = c.Get(ctx, "a"); err == nil || c.Len() != 1 {
+		t.Fatalf("del failed, len: %d", c.Len())
+	}
+}
+
+func TestCache_Expiration(t *testing.T) {
+	c := New[string]()
+	_ = c.Set(ctx, cache.NewEntry("a", "1", 10*time.Millisecond), cache.NewEntry("b", "2", 0))
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.Get(ctx, "a"); err == nil {
+		t.Fatalf("unexpected hit on expired entry")
+	}
+	if _, err := c.Get(ctx, "b"); err != nil {
+		t.Fatalf("unexpected miss: %v", err)
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	c := New[int](WithShards(1), WithMaxEntries(2))
+	_ = c.Set(ctx, cache.NewEntry("a", 1, 0), cache.NewEntry("b", 2, 0))
+	_, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, cache.NewEntry("c", 3, 0))
+	if _, err := c.Get(ctx, "b"); err == nil {
+		t.Fatalf("least recently used entry not evicted")
+	}
+	if _, err := c.Get(ctx, "a"); err != nil || c.Len() != 2 {
+		t.Fatalf("unexpected eviction, len: %d", c.Len())
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	for _, maxEntries := range []int{1, 3, 100} {
+		c := New[int](WithMaxEntries(maxEntries))
+		for i := 0; i < 1000; i++ {
+			_ = c.Set(ctx, cache.NewEntry(fmt.Sprint(i), i, 0))
+		}
+		if c.Len() > maxEntries {
+			t.Fatalf("max entries %d exceeded: %d", maxEntries, c.Len())
+		}
+	}
+}
+
+func TestCache_Sweep(t *testing.T) {
+	c := New[int](WithShards(1))
+	for i := 0; i < 100; i++ {
+		_ = c.Set(ctx, cache.NewEntry(fmt.Sprint("expiring", i), i, time.Millisecond))
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		_ = c.Set(ctx, cache.NewEntry(fmt.Sprint(i), i, 0))
+	}
+	if c.Len() != 100 {
+		t.Fatalf("expired entries not swept: %d", c.Len())
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	c := New[int](WithMaxEntries(100))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprint(j % 200)
+				_ = c.Set(ctx, cache.NewEntry(key, j, 0))
+				_, _ = c.Get(ctx, key)
+				if j%10 == i {
+					_ = c.Del(ctx, key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if c.Len() > 100 {
+		t.Fatalf("max entries exceeded: %d", c.Len())
+	}
+}
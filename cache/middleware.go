@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Middleware decorates a Cacher.
+type Middleware[V any] func(Cacher[V]) Cacher[V]
+
+// Chain composes middlewares into one, the first being the outermost:
+// Chain(a, b)(c) is a(b(c)).
+func Chain[V any](middlewares ...Middleware[V]) Middleware[V] {
+	return func(c Cacher[V]) Cacher[V] {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			c = middlewares[i](c)
+		}
+		return c
+	}
+}
+
+// Op names a Cacher operation.
+type Op string
+
+const (
+	OpGet  Op = "get"
+	OpMGet Op = "mget"
+	OpSet  Op = "set"
+	OpDel  Op = "del"
+)
+
+// Timeout bounds every operation by timeout.
+func Timeout[V any](timeout time.Duration) Middleware[V] {
+	return func(c Cacher[V]) Cacher[V] {
+		return &interceptor[V]{
+			cacher: c,
+			around: func(ctx context.Context, op Op, keys int, call func(ctx context.Context) error) error {
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				return call(ctx)
+			},
+		}
+	}
+}
+
+// Retry makes up to attempts calls of an operation failing with an error other
+// than ErrNotFound or a context error, waiting backoff before the first retry
+// and doubling the wait for each next one.
+func Retry[V any](attempts int, backoff time.Duration) Middleware[V] {
+	return func(c Cacher[V]) Cacher[V] {
+		return &interceptor[V]{
+			cacher: c,
+			around: func(ctx context.Context, op Op, keys int, call func(ctx context.Context) error) error {
+				wait := backoff
+				for attempt := 1; ; attempt++ {
+					err := call(ctx)
+					if err == nil || attempt >= attempts || !retryable(err) {
+						return err
+					}
+					timer := time.NewTimer(wait)
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+						return err
+					case <-timer.C:
+					}
+					wait *= 2
+				}
+			},
+		}
+	}
+}
+
+func retryable(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Observer receives the outcome of every operation, keys being the number of
+// keys or entries it was given.
+type Observer interface {
+	Observe(op Op, keys int, duration time.Duration, err error)
+}
+
+type ObserverFunc func(op Op, keys int, duration time.Duration, err error)
+
+func (f ObserverFunc) Observe(op Op, keys int, duration time.Duration, err error) {
+	f(op, keys, duration, err)
+}
+
+// Metrics reports every operation to observer.
+func Metrics[V any](observer Observer) Middleware[V] {
+	return func(c Cacher[V]) Cacher[V] {
+		return &interceptor[V]{
+			cacher: c,
+			around: func(ctx context.Context, op Op, keys int, call func(ctx context.Context) error) error {
+				start := time.Now()
+				err := call(ctx)
+				observer.Observe(op, keys, time.Since(start), err)
+				return err
+			},
+		}
+	}
+}
+
+// Logging logs every operation to logger at debug level, and the failed ones,
+// misses excluded, at warn level.
+func Logging[V any](logger *slog.Logger) Middleware[V] {
+	return func(c Cacher[V]) Cacher[V] {
+		return &interceptor[V]{
+			cacher: c,
+			around: func(ctx context.Context, op Op, keys int, call func(ctx context.Context) error) error {
+				start := time.Now()
+				err := call(ctx)
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					logger.WarnContext(ctx, "cache operation failed", "op", op, "keys", keys, "duration", time.Since(start), "error", err)
+				} else {
+					logger.DebugContext(ctx, "cache operation", "op", op, "keys", keys, "duration", time.Since(start))
+				}
+				return err
+			},
+		}
+	}
+}
+
+// interceptor runs every operation of the cacher it wraps through around.
+type interceptor[V any] struct {
+	cacher Cacher[V]
+	around func(ctx context.Context, op Op, keys int, call func(ctx context.Context) error) error
+}
+
+func (i *interceptor[V]) Get(ctx context.Context, key string) (Entry[V], error) {
+	var e Entry[V]
+	err := i.around(ctx, OpGet, 1, func(ctx context.Context) (err error) {
+		e, err = i.cacher.Get(ctx, key)
+		return err
+	})
+	return e, err
+}
+
+func (i *interceptor[V]) MGet(ctx context.Context, keys []string) ([]Entry[V], error) {
+	var entries []Entry[V]
+	err := i.around(ctx, OpMGet, len(keys), func(ctx context.Context) (err error) {
+		entries, err = i.cacher.MGet(ctx, keys)
+		return err
+	})
+	return entries, err
+}
+
+func (i *interceptor[V]) Set(ctx context.Context, entries ...Entry[V]) error {
+	return i.around(ctx, OpSet, len(entries), func(ctx context.Context) error {
+		return i.cacher.Set(ctx, entries...)
+	})
+}
+
+func (i *interceptor[V]) Del(ctx context.Context, keys ...string) error {
+	return i.around(ctx, OpDel, len(keys), func(ctx context.Context) error {
+		return i.cacher.Del(ctx, keys...)
+	})
+}
+
+func (i *interceptor[V]) KeepsMetadata() bool {
+	return KeepsMetadata(i.cacher)
+}
+
+// KeyPrefix prepends prefix to the keys of the cacher it wraps, entries are
+// returned with their unprefixed key.
+func KeyPrefix[V any](prefix string) Middleware[V] {
+	return func(c Cacher[V]) Cacher[V] {
+		return &prefixCacher[V]{cacher: c, prefix: prefix}
+	}
+}
+
+type prefixCacher[V any] struct {
+	cacher Cacher[V]
+	prefix string
+}
+
+func (c *prefixCacher[V]) Get(ctx context.Context, key string) (Entry[V], error) {
+	e, err := c.cacher.Get(ctx, c.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	return rekey(key, e), nil
+}
+
+func (c *prefixCacher[V]) MGet(ctx context.Context, keys []string) ([]Entry[V], error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	entries, err := c.cacher.MGet(ctx, prefixed)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if e != nil && i < len(keys) {
+			entries[i] = rekey(keys[i], e)
+		}
+	}
+	return entries, nil
+}
+
+func (c *prefixCacher[V]) Set(ctx context.Context, entries ...Entry[V]) error {
+	prefixed := make([]Entry[V], len(entries))
+	for i, e := range entries {
+		prefixed[i] = rekey(c.prefix+e.Key(), e)
+	}
+	return c.cacher.Set(ctx, prefixed...)
+}
+
+func (c *prefixCacher[V]) Del(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.cacher.Del(ctx, prefixed...)
+}
+
+func (c *prefixCacher[V]) KeepsMetadata() bool {
+	return KeepsMetadata(c.cacher)
+}
+
+// rekey returns e under key, keeping its metadata.
+func rekey[V any](key string, e Entry[V]) Entry[V] {
+	return NewEntryWithMetadata(key, e.Value(), e.Expiration(), MetadataOf(e))
+}
@@ -0,0 +1,138 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+// flakyCacher fails the first failures operations, and waits for delay or the
+// end of the context before each one.
+type flakyCacher struct {
+	cache.Cacher[string]
+	failures int
+	calls    int
+	delay    time.Duration
+}
+
+var errFlaky = errors.New("flaky")
+
+func (c *flakyCacher) call(ctx context.Context) error {
+	c.calls++
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c.calls <= c.failures {
+		return errFlaky
+	}
+	return nil
+}
+
+func (c *flakyCacher) Get(ctx context.Context, key string) (cache.Entry[string], error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return c.Cacher.Get(ctx, key)
+}
+
+func (c *flakyCacher) Set(ctx context.Context, entries ...cache.Entry[string]) error {
+	if err := c.call(ctx); err != nil {
+		return err
+	}
+	return c.Cacher.Set(ctx, entries...)
+}
+
+func TestChain(t *testing.T) {
+	var ops []string
+	observer := func(name string) cache.Middleware[string] {
+		return cache.Metrics[string](cache.ObserverFunc(func(op cache.Op, keys int, d time.Duration, err error) {
+			ops = append(ops, name+":"+string(op))
+		}))
+	}
+	raw := memory.New[string]()
+	c := cache.Chain(observer("outer"), cache.KeyPrefix[string]("p:"), observer("inner"))(raw)
+	_ = c.Set(ctx, cache.NewEntry("k", "v", 0))
+	if !slices.Equal(ops, []string{"inner:set", "outer:set"}) {
+		t.Fatalf("unexpected ops: %v", ops)
+	}
+	if e, err := raw.Get(ctx, "p:k"); err != nil || e.Value() != "v" {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+	if c = cache.Chain[string]()(raw); c != raw {
+		t.Fatalf("empty chain wrapped the cacher")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := cache.Timeout[string](10 * time.Millisecond)(&flakyCacher{Cacher: memory.New[string](), delay: time.Second})
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	flaky := &flakyCacher{Cacher: memory.New[string](), failures: 2}
+	c := cache.Retry[string](3, time.Millisecond)(flaky)
+	if err := c.Set(ctx, cache.NewEntry("k", "v", 0)); err != nil || flaky.calls != 3 {
+		t.Fatalf("unexpected err: %v, calls: %d", err, flaky.calls)
+	}
+	// misses are not retried
+	flaky.calls, flaky.failures = 0, 0
+	if _, err := c.Get(ctx, "absent"); !errors.Is(err, cache.ErrNotFound) || flaky.calls != 1 {
+		t.Fatalf("unexpected err: %v, calls: %d", err, flaky.calls)
+	}
+	flaky.calls, flaky.failures = 0, 5
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, errFlaky) || flaky.calls != 3 {
+		t.Fatalf("unexpected err: %v, calls: %d", err, flaky.calls)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := cache.Logging[string](logger)(&flakyCacher{Cacher: memory.New[string](), failures: 1})
+	_, _ = c.Get(ctx, "k")
+	_, _ = c.Get(ctx, "k")
+	out := buf.String()
+	if !strings.Contains(out, `level=WARN msg="cache operation failed" op=get keys=1`) || !strings.Contains(out, `level=DEBUG msg="cache operation" op=get keys=1`) {
+		t.Fatalf("unexpected logs: %s", out)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	raw := memory.New[string]()
+	c := cache.KeyPrefix[string]("p:")(raw)
+	_ = c.Set(ctx,
+		cache.NewEntryWithMetadata("a", "1", 0, cache.Metadata{Version: 7}),
+		cache.NewEntry("b", "2", 0),
+	)
+	e, err := c.Get(ctx, "a")
+	if err != nil || e.Key() != "a" || e.Value() != "1" || cache.MetadataOf(e).Version != 7 {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+	entries, err := c.MGet(ctx, []string{"b", "c"})
+	if err != nil || entries[0].Key() != "b" || entries[1] != nil {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	_ = c.Del(ctx, "a")
+	if _, err = raw.Get(ctx, "p:a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestChain_KeepsMetadata(t *testing.T) {
+	c := cache.Chain(cache.KeyPrefix[int]("p:"), cache.Timeout[int](time.Second))(memory.New[int]())
+	if !cache.KeepsMetadata(c) {
+		t.Fatalf("metadata support not forwarded")
+	}
+}
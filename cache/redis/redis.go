@@ -0,0 +1,271 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/codec"
+	"github.com/xianlianghe0123/anycache/internal/pool"
+)
+
+type Option func(o *options)
+
+type options struct {
+	password    string
+	db          int
+	poolSize    int
+	dialTimeout time.Duration
+	ioTimeout   time.Duration
+	metadata    bool
+}
+
+func WithPassword(password string) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+func WithDB(db int) Option {
+	return func(o *options) {
+		o.db = db
+	}
+}
+
+func WithPoolSize(size int) Option {
+	return func(o *options) {
+		o.poolSize = size
+	}
+}
+
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithIOTimeout bounds every round trip whose context has no deadline.
+func WithIOTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.ioTimeout = timeout
+	}
+}
+
+// WithMetadata stores the entry metadata along with the values, see
+// cache.MarshalEntry. By default values are stored as the bare codec payload,
+// readable by any client using the same codec, and metadata is dropped.
+func WithMetadata() Option {
+	return func(o *options) {
+		o.metadata = true
+	}
+}
+
+// Cache is a cache.Cacher storing codec encoded values in redis.
+type Cache[V any] struct {
+	pool     *pool.Pool
+	codec    codec.Codec[V]
+	metadata bool
+}
+
+var _ cache.Cacher[any] = (*Cache[any])(nil)
+
+func New[V any](addr string, c codec.Codec[V], opts ...Option) *Cache[V] {
+	if c == nil {
+		panic("codec is nil")
+	}
+	o := &options{
+		poolSize:    10,
+		dialTimeout: 5 * time.Second,
+		ioTimeout:   3 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Cache[V]{
+		pool: pool.New(pool.Config{
+			Addr:        addr,
+			Size:        o.poolSize,
+			DialTimeout: o.dialTimeout,
+			IOTimeout:   o.ioTimeout,
+			Init: func(conn *pool.Conn) error {
+				return initConn(conn, o)
+			},
+		}),
+		codec:    c,
+		metadata: o.metadata,
+	}
+}
+
+func (c *Cache[V]) Get(ctx context.Context, key string) (cache.Entry[V], error) {
+	replies, err := c.do(ctx, [][]byte{[]byte("GET"), []byte(key)})
+	if err != nil {
+		return nil, err
+	}
+	return c.decode(key, replies[0])
+}
+
+func (c *Cache[V]) MGet(ctx context.Context, keys []string) ([]cache.Entry[V], error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	args := make([][]byte, 0, len(keys)+1)
+	args = append(args, []byte("MGET"))
+	for _, key := range keys {
+		args = append(args, []byte(key))
+	}
+	replies, err := c.do(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := replies[0].([]any)
+	if !ok || len(values) != len(keys) {
+		return nil, fmt.Errorf("redis: unexpected MGET reply %T", replies[0])
+	}
+	entries := make([]cache.Entry[V], len(keys))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		entries[i], err = c.decode(keys[i], value)
+		if errors.Is(err, cache.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Set pipelines one SET per entry, with a PX argument when the entry expires.
+func (c *Cache[V]) Set(ctx context.Context, entries ...cache.Entry[V]) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	cmds := make([][][]byte, 0, len(entries))
+	for _, e := range entries {
+		data, err := c.marshal(e)
+		if err != nil {
+			return err
+		}
+		cmd := [][]byte{[]byte("SET"), []byte(e.Key()), data}
+		if e.Expiration() > 0 {
+			ms := max(e.Expiration().Milliseconds(), 1)
+			cmd = append(cmd, []byte("PX"), strconv.AppendInt(nil, ms, 10))
+		}
+		cmds = append(cmds, cmd)
+	}
+	_, err := c.do(ctx, cmds...)
+	return err
+}
+
+func (c *Cache[V]) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([][]byte, 0, len(keys)+1)
+	args = append(args, []byte("DEL"))
+	for _, key := range keys {
+		args = append(args, []byte(key))
+	}
+	_, err := c.do(ctx, args)
+	return err
+}
+
+// KeepsMetadata reports whether the cache was created WithMetadata.
+func (c *Cache[V]) KeepsMetadata() bool {
+	return c.metadata
+}
+
+func (c *Cache[V]) Close() error {
+	return c.pool.Close()
+}
+
+func (c *Cache[V]) decode(key string, reply any) (cache.Entry[V], error) {
+	if reply == nil {
+		return nil, cache.ErrNotFound
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply %T for key %s", reply, key)
+	}
+	return c.unmarshal(key, data)
+}
+
+func (c *Cache[V]) marshal(e cache.Entry[V]) ([]byte, error) {
+	if c.metadata {
+		return cache.MarshalEntry(e, c.codec)
+	}
+	return c.codec.Marshal(e.Value())
+}
+
+func (c *Cache[V]) unmarshal(key string, data []byte) (cache.Entry[V], error) {
+	if c.metadata {
+		return cache.UnmarshalEntry(key, data, 0, c.codec)
+	}
+	value, err := c.codec.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewEntry(key, value, 0), nil
+}
+
+// do writes cmds in one pipeline and reads a reply for each of them. The first
+// error reply is returned after all replies are consumed, so the connection
+// stays usable.
+func (c *Cache[V]) do(ctx context.Context, cmds ...[][]byte) ([]any, error) {
+	conn, err := c.pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := pipeline(conn, cmds...)
+	var replyErr Error
+	if errors.As(err, &replyErr) {
+		c.pool.Put(conn, nil)
+	} else {
+		c.pool.Put(conn, err)
+	}
+	return replies, err
+}
+
+func pipeline(conn *pool.Conn, cmds ...[][]byte) ([]any, error) {
+	for _, cmd := range cmds {
+		if err := writeCommand(conn.W, cmd...); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.W.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]any, len(cmds))
+	var replyErr error
+	for i := range replies {
+		reply, err := readReply(conn.R)
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := reply.(Error); ok && replyErr == nil {
+			replyErr = e
+		}
+		replies[i] = reply
+	}
+	return replies, replyErr
+}
+
+func initConn(conn *pool.Conn, o *options) error {
+	var cmds [][][]byte
+	if o.password != "" {
+		cmds = append(cmds, [][]byte{[]byte("AUTH"), []byte(o.password)})
+	}
+	if o.db != 0 {
+		cmds = append(cmds, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(o.db))})
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	_, err := pipeline(conn, cmds...)
+	return err
+}
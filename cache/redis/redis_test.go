@@ -0,0 +1,198 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/codec"
+)
+
+var ctx = context.Background()
+
+type fakeServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	data     map[string]string
+	expireAt map[string]time.Time
+	commands []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, data: map[string]string{}, expireAt: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]any) {
+			args = append(args, string(arg.([]byte)))
+		}
+		s.handle(w, args)
+		if r.Buffered() == 0 {
+			_ = w.Flush()
+		}
+	}
+}
+
+func (s *fakeServer) handle(w *bufio.Writer, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, strings.Join(args, " "))
+	get := func(key string) {
+		v, ok := s.data[key]
+		if exp, has := s.expireAt[key]; has && time.Now().After(exp) {
+			ok = false
+		}
+		if !ok {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+	}
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != "secret" {
+			w.WriteString("-WRONGPASS invalid password\r\n")
+			return
+		}
+		w.WriteString("+OK\r\n")
+	case "GET":
+		get(args[1])
+	case "MGET":
+		w.WriteString("*" + strconv.Itoa(len(args)-1) + "\r\n")
+		for _, key := range args[1:] {
+			get(key)
+		}
+	case "SET":
+		s.data[args[1]] = args[2]
+		delete(s.expireAt, args[1])
+		if len(args) == 5 {
+			ms, _ := strconv.Atoi(args[4])
+			s.expireAt[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		w.WriteString("+OK\r\n")
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(s.data, key)
+		}
+		w.WriteString(":" + strconv.Itoa(len(args)-1) + "\r\n")
+	default:
+		w.WriteString("-ERR unknown command\r\n")
+	}
+}
+
+func TestCache(t *testing.T) {
+	s := newFakeServer(t)
+	c := New[string](s.ln.Addr().String(), codec.String{})
+	defer c.Close()
+
+	if _, err := c.Get(ctx, "a"); err == nil {
+		t.Fatalf("unexpected hit")
+	}
+	err := c.Set(ctx, cache.NewEntry("a", "1", 0), cache.NewEntry("b", "2\r\n", 1500*time.Microsecond))
+	if err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	s.mu.Lock()
+	commands := s.commands
+	s.mu.Unlock()
+	if commands[1] != "SET a 1" || commands[2] != "SET b 2\r\n PX 1" {
+		t.Fatalf("unexpected commands: %q", commands)
+	}
+	e, err := c.Get(ctx, "a")
+	if err != nil || e.Value() != "1" {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+	entries, err := c.MGet(ctx, []string{"a", "c", "b"})
+	if err != nil || entries[0].Value() != "1" || entries[1] != nil || entries[2].Value() != "2\r\n" {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if entries, _ = c.MGet(ctx, []string{"b"}); entries[0] != nil {
+		t.Fatalf("entry not expired")
+	}
+	if err = c.Del(ctx, "a", "b"); err != nil {
+		t.Fatalf("del failed: %v", err)
+	}
+	if _, err = c.Get(ctx, "a"); err == nil {
+		t.Fatalf("unexpected hit after del")
+	}
+}
+
+func TestCache_Metadata(t *testing.T) {
+	s := newFakeServer(t)
+	md := cache.Metadata{StoredAt: time.Unix(1700000000, 0), Version: 7, Origin: cache.OriginLoader}
+	// metadata is dropped by default, the value being stored alone
+	c := New[string](s.ln.Addr().String(), codec.String{})
+	defer c.Close()
+	_ = c.Set(ctx, cache.NewEntryWithMetadata("a", "1", 0, md))
+	if e, err := c.Get(ctx, "a"); err != nil || e.Value() != "1" || cache.MetadataOf(e) != (cache.Metadata{}) || cache.KeepsMetadata[string](c) {
+		t.Fatalf("unexpected entry: %v, err: %v", e, err)
+	}
+
+	m := New[string](s.ln.Addr().String(), codec.String{}, WithMetadata())
+	defer m.Close()
+	_ = m.Set(ctx, cache.NewEntryWithMetadata("b", "2", 0, md), cache.NewEntryWithMetadata("c", "", 0, cache.Metadata{NotFound: true}))
+	entries, err := m.MGet(ctx, []string{"a", "b", "c"})
+	if err != nil || entries[0].Value() != "1" || entries[1].Value() != "2" || !cache.KeepsMetadata[string](m) {
+		t.Fatalf("unexpected entries: %v, err: %v", entries, err)
+	}
+	if got := cache.MetadataOf(entries[1]); !got.StoredAt.Equal(md.StoredAt) || got.Version != 7 || got.Origin != cache.OriginLoader {
+		t.Fatalf("metadata not preserved: %+v", got)
+	}
+	if !cache.MetadataOf(entries[2]).NotFound {
+		t.Fatalf("tombstone not preserved: %v", entries[2])
+	}
+}
+
+func TestCache_Auth(t *testing.T) {
+	s := newFakeServer(t)
+	c := New[string](s.ln.Addr().String(), codec.String{}, WithPassword("wrong"))
+	if _, err := c.Get(ctx, "a"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	c = New[string](s.ln.Addr().String(), codec.String{}, WithPassword("secret"), WithPoolSize(2))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := strconv.Itoa(i)
+			if err := c.Set(ctx, cache.NewEntry(key, key, 0)); err != nil {
+				t.Errorf("set failed: %v", err)
+			}
+			if e, err := c.Get(ctx, key); err != nil || e.Value() != key {
+				t.Errorf("unexpected entry: %v, err: %v", e, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,114 @@
+package tinylfu
+
+// sketch is a count-min sketch with 4-bit saturating counters guarded by a
+// doorkeeper bloom filter, so keys seen only once never reach the counters.
+// Counters are halved and the doorkeeper cleared every sampleSize increments
+// to let the frequency estimate age.
+type sketch struct {
+	counters   [4][]uint8
+	mask       uint64
+	doorkeeper []uint64
+	additions  int
+	sampleSize int
+}
+
+var seeds = [4]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+func newSketch(width int) *sketch {
+	n := 16
+	for n < width {
+		n <<= 1
+	}
+	s := &sketch{
+		mask:       uint64(n - 1),
+		doorkeeper: make([]uint64, (n+63)/64),
+		sampleSize: 10 * n,
+	}
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, n)
+	}
+	return s
+}
+
+func (s *sketch) increment(h uint64) {
+	if !s.admitDoorkeeper(h) {
+		return
+	}
+	for i := range s.counters {
+		idx := s.index(h, i)
+		if s.counters[i][idx] < 15 {
+			s.counters[i][idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *sketch) estimate(h uint64) int {
+	least := uint8(15)
+	for i := range s.counters {
+		if c := s.counters[i][s.index(h, i)]; c < least {
+			least = c
+		}
+	}
+	if s.inDoorkeeper(h) {
+		return int(least) + 1
+	}
+	return int(least)
+}
+
+func (s *sketch) reset() {
+	s.additions /= 2
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] >>= 1
+		}
+	}
+	for i := range s.doorkeeper {
+		s.doorkeeper[i] = 0
+	}
+}
+
+func (s *sketch) index(h uint64, i int) uint64 {
+	h = (h ^ seeds[i]) * 0x9e3779b97f4a7c15
+	return (h ^ h>>32) & s.mask
+}
+
+// admitDoorkeeper records h in the doorkeeper and reports whether it was
+// already present.
+func (s *sketch) admitDoorkeeper(h uint64) bool {
+	present := true
+	for _, bit := range s.doorkeeperBits(h) {
+		w, m := bit/64, uint64(1)<<(bit%64)
+		if s.doorkeeper[w]&m == 0 {
+			present = false
+			s.doorkeeper[w] |= m
+		}
+	}
+	return present
+}
+
+func (s *sketch) inDoorkeeper(h uint64) bool {
+	for _, bit := range s.doorkeeperBits(h) {
+		if s.doorkeeper[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sketch) doorkeeperBits(h uint64) [2]uint64 {
+	size := uint64(len(s.doorkeeper) * 64)
+	return [2]uint64{h % size, (h >> 32) % size}
+}
+
+func hash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
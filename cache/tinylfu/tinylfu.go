@@ -0,0 +1,276 @@
+package tinylfu
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+type segment int
+
+const (
+	segmentWindow segment = iota
+	segmentProbation
+	segmentProtected
+)
+
+type Option[V any] func(o *options[V])
+
+type options[V any] struct {
+	cost            func(entry cache.Entry[V]) int64
+	expectedEntries int
+	windowPercent   int
+}
+
+// WithCost sets the function measuring the bytes an entry accounts for
+// against the budget.
+func WithCost[V any](cost func(entry cache.Entry[V]) int64) Option[V] {
+	return func(o *options[V]) {
+		o.cost = cost
+	}
+}
+
+// WithExpectedEntries sizes the frequency sketch, it should be close to the
+// number of entries the budget is expected to hold.
+func WithExpectedEntries[V any](n int) Option[V] {
+	return func(o *options[V]) {
+		o.expectedEntries = n
+	}
+}
+
+// WithWindowPercent sets the share of the budget given to the admission
+// window, the rest belongs to the segmented LRU main space.
+func WithWindowPercent[V any](percent int) Option[V] {
+	return func(o *options[V]) {
+		o.windowPercent = percent
+	}
+}
+
+// DefaultCost charges the key length plus the length of string and []byte
+// values, or the shallow size of any other value.
+func DefaultCost[V any](entry cache.Entry[V]) int64 {
+	var size int64
+	switch v := any(entry.Value()).(type) {
+	case string:
+		size = int64(len(v))
+	case []byte:
+		size = int64(len(v))
+	default:
+		value := entry.Value()
+		size = int64(unsafe.Sizeof(value))
+	}
+	return int64(len(entry.Key())) + size
+}
+
+type node[V any] struct {
+	entry    cache.Entry[V]
+	expireAt time.Time
+	cost     int64
+	hash     uint64
+	segment  segment
+}
+
+// Cache is a cost bounded cache.Cacher using the W-TinyLFU policy: new
+// entries land in a small LRU window and are only admitted to the main
+// segmented LRU when their estimated access frequency beats the entries they
+// would evict.
+type Cache[V any] struct {
+	mu     sync.Mutex
+	cost   func(entry cache.Entry[V]) int64
+	sketch *sketch
+	items  map[string]*list.Element
+
+	segments [3]*list.List
+	costs    [3]int64
+
+	maxCost         int64
+	windowBudget    int64
+	protectedBudget int64
+}
+
+var _ cache.Cacher[any] = (*Cache[any])(nil)
+
+func New[V any](maxCost int64, opts ...Option[V]) *Cache[V] {
+	if maxCost <= 0 {
+		panic("maxCost must be positive")
+	}
+	o := &options[V]{
+		cost:            DefaultCost[V],
+		expectedEntries: 10000,
+		windowPercent:   1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.cost == nil {
+		panic("cost is nil")
+	}
+	if o.windowPercent <= 0 || o.windowPercent >= 100 {
+		panic("windowPercent must be in (0, 100)")
+	}
+	windowBudget := max(maxCost*int64(o.windowPercent)/100, 1)
+	c := &Cache[V]{
+		cost:            o.cost,
+		sketch:          newSketch(o.expectedEntries),
+		items:           make(map[string]*list.Element),
+		maxCost:         maxCost,
+		windowBudget:    windowBudget,
+		protectedBudget: (maxCost - windowBudget) * 8 / 10,
+	}
+	for i := range c.segments {
+		c.segments[i] = list.New()
+	}
+	return c
+}
+
+func (c *Cache[V]) Get(ctx context.Context, key string) (cache.Entry[V], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.get(key, time.Now())
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	return e, nil
+}
+
+func (c *Cache[V]) MGet(ctx context.Context, keys []string) ([]cache.Entry[V], error) {
+	now := time.Now()
+	entries := make([]cache.Entry[V], len(keys))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, key := range keys {
+		if e, ok := c.get(key, now); ok {
+			entries[i] = e
+		}
+	}
+	return entries, nil
+}
+
+func (c *Cache[V]) Set(ctx context.Context, entries ...cache.Entry[V]) error {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range entries {
+		c.set(e, now)
+	}
+	return nil
+}
+
+func (c *Cache[V]) Del(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.remove(elem)
+		}
+	}
+	return nil
+}
+
+// KeepsMetadata returns true, entries are kept as they are given.
+func (c *Cache[V]) KeepsMetadata() bool {
+	return true
+}
+
+// Cost returns the total cost of the stored entries.
+func (c *Cache[V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.costs[segmentWindow] + c.costs[segmentProbation] + c.costs[segmentProtected]
+}
+
+func (c *Cache[V]) get(key string, now time.Time) (cache.Entry[V], bool) {
+	h := hash(key)
+	c.sketch.increment(h)
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	n := elem.Value.(*node[V])
+	if !n.expireAt.IsZero() && !now.Before(n.expireAt) {
+		c.remove(elem)
+		return nil, false
+	}
+	switch n.segment {
+	case segmentProbation:
+		c.move(elem, segmentProtected)
+		for c.costs[segmentProtected] > c.protectedBudget {
+			c.move(c.segments[segmentProtected].Back(), segmentProbation)
+		}
+	default:
+		c.segments[n.segment].MoveToFront(elem)
+	}
+	return n.entry, true
+}
+
+func (c *Cache[V]) set(e cache.Entry[V], now time.Time) {
+	n := &node[V]{
+		entry:   e,
+		cost:    c.cost(e),
+		hash:    hash(e.Key()),
+		segment: segmentWindow,
+	}
+	if e.Expiration() > 0 {
+		n.expireAt = now.Add(e.Expiration())
+	}
+	if elem, ok := c.items[e.Key()]; ok {
+		c.remove(elem)
+	}
+	if n.cost > c.maxCost-c.windowBudget {
+		return
+	}
+	c.sketch.increment(n.hash)
+	c.items[e.Key()] = c.segments[segmentWindow].PushFront(n)
+	c.costs[segmentWindow] += n.cost
+	for c.costs[segmentWindow] > c.windowBudget {
+		c.admit(c.segments[segmentWindow].Back())
+	}
+}
+
+// admit moves the window victim candidate into probation if its frequency is
+// higher than every main space entry that has to be evicted to make room for
+// it, otherwise the candidate is dropped.
+func (c *Cache[V]) admit(candidate *list.Element) {
+	cn := candidate.Value.(*node[V])
+	mainBudget := c.maxCost - c.windowBudget
+	need := c.costs[segmentProbation] + c.costs[segmentProtected] + cn.cost - mainBudget
+	if need > 0 {
+		freq := c.sketch.estimate(cn.hash)
+		var victims []*list.Element
+		for _, seg := range []segment{segmentProbation, segmentProtected} {
+			for elem := c.segments[seg].Back(); elem != nil && need > 0; elem = elem.Prev() {
+				vn := elem.Value.(*node[V])
+				if c.sketch.estimate(vn.hash) >= freq {
+					c.remove(candidate)
+					return
+				}
+				victims = append(victims, elem)
+				need -= vn.cost
+			}
+		}
+		for _, elem := range victims {
+			c.remove(elem)
+		}
+	}
+	c.move(candidate, segmentProbation)
+}
+
+func (c *Cache[V]) move(elem *list.Element, to segment) {
+	n := elem.Value.(*node[V])
+	c.segments[n.segment].Remove(elem)
+	c.costs[n.segment] -= n.cost
+	n.segment = to
+	c.items[n.entry.Key()] = c.segments[to].PushFront(n)
+	c.costs[to] += n.cost
+}
+
+func (c *Cache[V]) remove(elem *list.Element) {
+	n := elem.Value.(*node[V])
+	c.segments[n.segment].Remove(elem)
+	c.costs[n.segment] -= n.cost
+	delete(c.items, n.entry.Key())
+}
@@ -0,0 +1,83 @@
+package tinylfu
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+var ctx = context.Background()
+
+func unitCost(cache.Entry[int]) int64 { return 1 }
+
+func TestCache_GetSetDel(t *testing.T) {
+	c := New[string](1 << 20)
+	if _, err := c.Get(ctx, "a"); err == nil {
+		t.Fatalf("unexpected hit")
+	}
+	_ = c.Set(ctx, cache.NewEntry("a", "1", 0), cache.NewEntry("b", "22", 0))
+	entries, _ := c.MGet(ctx, []string{"a", "c", "b"})
+	if entries[0].Value() != "1" || entries[1] != nil || entries[2].Value() != "22" {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+	if c.Cost() != 5 {
+		t.Fatalf("unexpected cost: %d", c.Cost())
+	}
+	_ = c.Del(ctx, "a")
+	if _, err := c.Get(ctx, "a"); err == nil || c.Cost() != 3 {
+		t.Fatalf("del failed, cost: %d", c.Cost())
+	}
+}
+
+func TestCache_Expiration(t *testing.T) {
+	c := New[string](1 << 20)
+	_ = c.Set(ctx, cache.NewEntry("a", "1", 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.Get(ctx, "a"); err == nil || c.Cost() != 0 {
+		t.Fatalf("unexpected hit on expired entry")
+	}
+}
+
+func TestCache_Budget(t *testing.T) {
+	c := New[string](100)
+	_ = c.Set(ctx, cache.NewEntry("big", string(make([]byte, 200)), 0))
+	if _, err := c.Get(ctx, "big"); err == nil {
+		t.Fatalf("entry over budget admitted")
+	}
+	for i := 0; i < 100; i++ {
+		_ = c.Set(ctx, cache.NewEntry(fmt.Sprintf("k%02d", i), "0123456", 0))
+	}
+	if c.Cost() > 100 {
+		t.Fatalf("budget exceeded: %d", c.Cost())
+	}
+}
+
+func TestCache_ScanResistance(t *testing.T) {
+	c := New[int](100, WithCost(unitCost), WithExpectedEntries[int](100))
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = fmt.Sprint("hot", i)
+		_ = c.Set(ctx, cache.NewEntry(hot[i], i, 0))
+	}
+	for round := 0; round < 5; round++ {
+		_, _ = c.MGet(ctx, hot)
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint("scan", i)
+		_ = c.Set(ctx, cache.NewEntry(key, i, 0))
+		_, _ = c.Get(ctx, key)
+	}
+	entries, _ := c.MGet(ctx, hot)
+	hits := 0
+	for _, e := range entries {
+		if e != nil {
+			hits++
+		}
+	}
+	if hits < len(hot)*9/10 {
+		t.Fatalf("hot keys evicted by scan, hits: %d", hits)
+	}
+}
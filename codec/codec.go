@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec converts values to and from the bytes kept by byte oriented backends.
+type Codec[V any] interface {
+	Marshal(v V) ([]byte, error)
+	Unmarshal(data []byte) (V, error)
+}
+
+var (
+	_ Codec[any]    = JSON[any]{}
+	_ Codec[any]    = Gob[any]{}
+	_ Codec[[]byte] = Bytes{}
+	_ Codec[string] = String{}
+)
+
+type JSON[V any] struct{}
+
+func (JSON[V]) Marshal(v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSON[V]) Unmarshal(data []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// Gob encodes each value as a self-contained gob stream, so types are
+// transmitted with every value.
+type Gob[V any] struct{}
+
+func (Gob[V]) Marshal(v V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Gob[V]) Unmarshal(data []byte) (V, error) {
+	var v V
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// Bytes stores []byte values as is.
+type Bytes struct{}
+
+func (Bytes) Marshal(v []byte) ([]byte, error) {
+	return v, nil
+}
+
+func (Bytes) Unmarshal(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+type String struct{}
+
+func (String) Marshal(v string) ([]byte, error) {
+	return []byte(v), nil
+}
+
+func (String) Unmarshal(data []byte) (string, error) {
+	return string(data), nil
+}
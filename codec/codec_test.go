@@ -0,0 +1,39 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+)
+
+type user struct {
+	ID   int
+	Name string
+	Tags []string
+}
+
+func roundTrip[V any](t *testing.T, c Codec[V], v V) {
+	t.Helper()
+	data, err := c.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal %T failed: %v", c, err)
+	}
+	got, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal %T failed: %v", c, err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Fatalf("%T round trip: got %v, want %v", c, got, v)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	u := user{ID: 1, Name: "a", Tags: []string{"x", "y"}}
+	roundTrip[user](t, JSON[user]{}, u)
+	roundTrip[user](t, Gob[user]{}, u)
+	roundTrip[*user](t, JSON[*user]{}, &u)
+	roundTrip[[]byte](t, Bytes{}, []byte{0, 1, 2})
+	roundTrip[string](t, String{}, "abc")
+	if _, err := (JSON[user]{}).Unmarshal([]byte("{")); err == nil {
+		t.Fatalf("unexpected success")
+	}
+}
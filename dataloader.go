@@ -0,0 +1,94 @@
+package anycache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type loaderBatch[K any, V any] struct {
+	ctx     context.Context
+	keys    []K
+	indices map[string]int
+	timer   *time.Timer
+	done    chan struct{}
+	results []Result[V]
+}
+
+// dataLoader collects the keys of concurrent loads for up to window or
+// maxBatchSize distinct keys and loads them with a single batch call.
+type dataLoader[K any, V any] struct {
+	window       time.Duration
+	maxBatchSize int
+	buildKey     func(key K) string
+	batchLoad    func(ctx context.Context, keys []K) []Result[V]
+	emptyValue   V
+
+	mu      sync.Mutex
+	pending *loaderBatch[K, V]
+}
+
+func (d *dataLoader[K, V]) load(ctx context.Context, key K) Result[V] {
+	cacheKey := d.buildKey(key)
+	d.mu.Lock()
+	b := d.pending
+	if b == nil {
+		b = &loaderBatch[K, V]{
+			// the batch outlives the caller that opened it
+			ctx:     context.WithoutCancel(ctx),
+			indices: make(map[string]int),
+			done:    make(chan struct{}),
+		}
+		d.pending = b
+		b.timer = time.AfterFunc(d.window, func() {
+			d.dispatch(b)
+		})
+	}
+	i, ok := b.indices[cacheKey]
+	if !ok {
+		i = len(b.keys)
+		b.indices[cacheKey] = i
+		b.keys = append(b.keys, key)
+	}
+	// a full batch is detached before unlocking, so no caller joins it anymore
+	full := d.maxBatchSize > 0 && len(b.keys) >= d.maxBatchSize
+	if full {
+		d.detach(b)
+	}
+	d.mu.Unlock()
+	if full {
+		d.run(b)
+	}
+
+	select {
+	case <-b.done:
+		return b.results[i]
+	case <-ctx.Done():
+		return Result[V]{Value: d.emptyValue, Err: ctx.Err()}
+	}
+}
+
+// dispatch runs b unless it has already been dispatched by the timer or by
+// reaching the batch size.
+func (d *dataLoader[K, V]) dispatch(b *loaderBatch[K, V]) {
+	d.mu.Lock()
+	pending := d.pending == b
+	if pending {
+		d.detach(b)
+	}
+	d.mu.Unlock()
+	if pending {
+		d.run(b)
+	}
+}
+
+// detach stops collecting keys into the pending batch b, d.mu must be held.
+func (d *dataLoader[K, V]) detach(b *loaderBatch[K, V]) {
+	d.pending = nil
+	b.timer.Stop()
+}
+
+func (d *dataLoader[K, V]) run(b *loaderBatch[K, V]) {
+	b.results = d.batchLoad(b.ctx, b.keys)
+	close(b.done)
+}
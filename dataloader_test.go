@@ -0,0 +1,101 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestGetDataLoader(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+	fetcher := New[int, string](memory.New[string]()).
+		WithDataLoader(20*time.Millisecond, 4).
+		WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+			mu.Lock()
+			batches = append(batches, slices.Clone(keys))
+			mu.Unlock()
+			if slices.Contains(keys, 78) {
+				return nil, errors.New("error")
+			}
+			values := make([]string, len(keys))
+			for i, k := range keys {
+				values[i] = fmt.Sprint(k)
+			}
+			return values, nil
+		}).Build()
+
+	get := func(keys ...int) ([]string, []error) {
+		var wg sync.WaitGroup
+		values, errs := make([]string, len(keys)), make([]error, len(keys))
+		for i, k := range keys {
+			wg.Add(1)
+			go func(i, k int) {
+				defer wg.Done()
+				values[i], errs[i] = fetcher.Get(ctx, k)
+			}(i, k)
+		}
+		wg.Wait()
+		return values, errs
+	}
+	// one batch, duplicated keys loaded once
+	values, errs := get(1, 2, 2, 3)
+	if !slices.Equal(values, []string{"1", "2", "2", "3"}) || len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("unexpected values: %v, batches: %v, errs: %v", values, batches, errs)
+	}
+	// cached
+	if values, _ = get(1, 2, 3); !slices.Equal(values, []string{"1", "2", "3"}) || len(batches) != 1 {
+		t.Fatalf("unexpected values: %v, batches: %v", values, batches)
+	}
+	// split by max batch size
+	batches = nil
+	if values, _ = get(4, 5, 6, 7, 8, 9); !slices.Equal(values, []string{"4", "5", "6", "7", "8", "9"}) || len(batches) != 2 {
+		t.Fatalf("unexpected values: %v, batches: %v", values, batches)
+	}
+	// batch failure is reported to every caller
+	batches = nil
+	_, errs = get(10, 78)
+	if errs[0] == nil || errs[1] == nil || len(batches) != 1 {
+		t.Fatalf("unexpected errs: %v, batches: %v", errs, batches)
+	}
+}
+
+func TestGetDataLoaderMaxBatchSize(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	fetcher := New[int, string](memory.New[string]()).
+		WithDataLoader(10*time.Millisecond, 3).
+		WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+			mu.Lock()
+			sizes = append(sizes, len(keys))
+			mu.Unlock()
+			values := make([]string, len(keys))
+			for i, k := range keys {
+				values[i] = fmt.Sprint(k)
+			}
+			return values, nil
+		}).Build()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			if v, err := fetcher.Get(ctx, k); err != nil || v != fmt.Sprint(k) {
+				t.Errorf("unexpected value: %s, err: %v", v, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, size := range sizes {
+		if size > 3 {
+			t.Fatalf("batch size exceeded: %v", sizes)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xianlianghe0123/anycache/bloom"
+)
+
+func TestFilter(t *testing.T) {
+	mapCache := NewMapCache[string]()
+	fetcher := New[int, string](mapCache).
+		WithNameSpace("test").
+		WithFilter(bloom.New(100, 0.001)).
+		WithFilterSeed(func(yield func(int) bool) {
+			for _, k := range []int{1, 2, 3} {
+				if !yield(k) {
+					return
+				}
+			}
+		}).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}).Build()
+	source := func() int64 { return fetcher.Stats().Loads }
+
+	if v, err := fetcher.Get(ctx, 1); err != nil || v != "1" || source() != 1 {
+		t.Fatalf("unexpected value: %s, err: %v, source: %d", v, err, source())
+	}
+	// rejected without loading
+	if _, err := fetcher.Get(ctx, 123); !errors.Is(err, ErrNotFound) || source() != 1 {
+		t.Fatalf("unexpected err: %v, source: %d", err, source())
+	}
+	results, err := fetcher.MGetWithMeta(ctx, []int{2, 123, 3})
+	if err != nil || !results[0].Found || !errors.Is(results[1].Err, ErrNotFound) || !results[2].Found || source() != 3 {
+		t.Fatalf("unexpected results: %v, err: %v, source: %d", results, err, source())
+	}
+	// learned from set
+	_ = fetcher.Set(ctx, 123, "123")
+	if v, err := fetcher.Get(ctx, 123); err != nil || v != "123" || source() != 3 {
+		t.Fatalf("unexpected value: %s, err: %v, source: %d", v, err, source())
+	}
+}
@@ -2,15 +2,22 @@ package anycache
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
 )
 
-func (a *anyCache[K, V]) get(ctx context.Context, key K) (V, error) {
+func (a *anyCache[K, V]) get(ctx context.Context, key K) Result[V] {
+	if a.filtered(key) {
+		return Result[V]{Value: a.emptyValue, Err: ErrNotFound}
+	}
 	switch a.strategy {
 	case StrategySourceFirst:
 		return a.getSourceFirst(ctx, key)
 	case StrategyCacheOnly:
-		return a.getCache(ctx, key)
+		return a.getCacheOnly(ctx, key)
 	case StrategyCacheFirst:
 		fallthrough
 	default:
@@ -18,42 +25,135 @@ func (a *anyCache[K, V]) get(ctx context.Context, key K) (V, error) {
 	}
 }
 
-func (a *anyCache[K, V]) getCacheFirst(ctx context.Context, key K) (V, error) {
+// getCacheFirst loads the key on a miss, serving the expired value instead
+// when the load fails within the stale grace period.
+func (a *anyCache[K, V]) getCacheFirst(ctx context.Context, key K) Result[V] {
+	cached := a.getCache(ctx, key)
+	if cached.Found && !cached.Stale || errors.Is(cached.Err, ErrNotFound) {
+		return cached
+	}
+	if cached.Err != nil && !errors.Is(cached.Err, cache.ErrNotFound) {
+		if a.cacheErrorMode == CacheErrorSurface {
+			return cached
+		}
+		a.logger.fallback(ctx, "cache get failed, loading from the source", "key", a.buildKey(key), "error", cached.Err)
+	}
+	loaded := a.getSource(ctx, key)
+	if loaded.Err != nil && !errors.Is(loaded.Err, ErrNotFound) && cached.Stale {
+		a.logger.fallback(ctx, "load failed, serving the stale value", "key", a.buildKey(key), "error", loaded.Err)
+		return cached
+	}
+	return loaded
+}
 
-	value, err := a.getCache(ctx, key)
-	if err == nil {
-		return value, nil
+// getSourceFirst falls back to the cache when loading fails, reporting the load
+// error on a miss and both errors when the cache fails too. A key the source
+// reports not found is not looked up in the cache.
+func (a *anyCache[K, V]) getSourceFirst(ctx context.Context, key K) Result[V] {
+	loaded := a.getSource(ctx, key)
+	if loaded.Err == nil || errors.Is(loaded.Err, ErrNotFound) {
+		return loaded
 	}
-	value, err = a.getSource(ctx, key)
-	if err != nil {
-		return value, err
+	a.logger.fallback(ctx, "load failed, reading from the cache", "key", a.buildKey(key), "error", loaded.Err)
+	cached := a.getCache(ctx, key)
+	if cached.Found || errors.Is(cached.Err, ErrNotFound) {
+		return cached
+	}
+	if errors.Is(cached.Err, cache.ErrNotFound) {
+		return Result[V]{Value: cached.Value, Err: loaded.Err}
 	}
-	return value, nil
+	return Result[V]{Value: cached.Value, Err: errors.Join(loaded.Err, cached.Err)}
 }
 
-func (a *anyCache[K, V]) getSourceFirst(ctx context.Context, key K) (V, error) {
-	value, err := a.getSource(ctx, key)
-	if err == nil {
-		return value, nil
+// getCacheOnly reports expired values as misses.
+func (a *anyCache[K, V]) getCacheOnly(ctx context.Context, key K) Result[V] {
+	cached := a.getCache(ctx, key)
+	if cached.Stale {
+		return Result[V]{Value: a.emptyValue, Err: cache.ErrNotFound}
 	}
-	return a.getCache(ctx, key)
+	return cached
 }
 
-func (a *anyCache[K, V]) getCache(ctx context.Context, key K) (V, error) {
-	value, err := a.cache.Get(ctx, a.buildKey(key))
+// getCache looks key up in the cache. A value kept past its expiration for the
+// stale grace period is returned marked stale.
+func (a *anyCache[K, V]) getCache(ctx context.Context, key K) Result[V] {
+	cacheKey := a.buildKey(key)
+	entry, err := a.cache.Get(ctx, cacheKey)
 	if err != nil {
-		return a.emptyValue, err
+		if errors.Is(err, cache.ErrNotFound) {
+			atomic.AddInt64(&a.stats.misses, 1)
+		}
+		return Result[V]{Value: a.emptyValue, Err: err}
+	}
+	md := cache.MetadataOf(entry)
+	now := time.Now()
+	if a.staleGrace > 0 && expired(entry, now) {
+		atomic.AddInt64(&a.stats.misses, 1)
+		return Result[V]{Value: entry.Value(), Found: true, From: FromCache, Stale: true, Metadata: md}
+	}
+	atomic.AddInt64(&a.stats.hits, 1)
+	if a.refresher != nil {
+		a.refresher.track(cacheKey, key, md.ExpiresAt)
 	}
-	atomic.AddInt64(&a.hit, 1)
-	return value.Value(), nil
+	if md.NotFound {
+		return Result[V]{Value: a.emptyValue, Err: ErrNotFound, Metadata: md}
+	}
+	if stale(entry, now) {
+		a.revalidate(ctx, []K{key})
+	}
+	return Result[V]{Value: entry.Value(), Found: true, From: FromCache, Metadata: md}
+}
+
+func (a *anyCache[K, V]) getSource(ctx context.Context, key K) Result[V] {
+	load := a.load
+	if a.dataLoader != nil {
+		load = a.dataLoader.load
+	}
+	if a.flight == nil {
+		return load(ctx, key)
+	}
+	result, err := a.flight.do(ctx, a.buildKey(key), func(ctx context.Context) (Result[V], error) {
+		result := load(ctx, key)
+		return result, result.Err
+	})
+	if err != nil && result.Err == nil {
+		return Result[V]{Value: a.emptyValue, Err: err}
+	}
+	return result
 }
 
-func (a *anyCache[K, V]) getSource(ctx context.Context, key K) (V, error) {
-	atomic.AddInt64(&a.source, 1)
-	value, err := a.loader.Load(ctx, key)
+func (a *anyCache[K, V]) load(ctx context.Context, key K) Result[V] {
+	atomic.AddInt64(&a.stats.loads, 1)
+	loadCtx, cancel := a.withLoadTimeout(ctx)
+	atomic.AddInt64(&a.stats.loaderCalls, 1)
+	loadCtx, span := a.tracer.start(loadCtx, SpanLoad, 1)
+	start := time.Now()
+	value, ttl, err := a.loader.LoadWithTTL(loadCtx, key)
+	a.stats.loadLatency.observeSince(start)
+	endSpan(span, err)
+	cancel()
+	cacheKey := a.buildKey(key)
 	if err != nil {
-		return value, err
+		if !errors.Is(err, ErrNotFound) {
+			atomic.AddInt64(&a.stats.loadErrors, 1)
+			a.logger.loadError(ctx, "load failed", err, "key", cacheKey)
+		}
+		if !a.cachesNotFound(err) {
+			return Result[V]{Value: value, Err: err}
+		}
+		e := a.newTombstone(cacheKey, cache.OriginLoader)
+		a.store(ctx, e)
+		return Result[V]{Value: value, Err: err, Metadata: cache.MetadataOf(e)}
+	}
+	a.addFilter(cacheKey)
+	e := a.newEntry(cacheKey, value, ttl, cache.OriginLoader)
+	a.store(ctx, e)
+	return Result[V]{Value: value, Found: true, From: FromSource, Metadata: cache.MetadataOf(e)}
+}
+
+// store caches the loaded entries, logging the failure no caller sees.
+func (a *anyCache[K, V]) store(ctx context.Context, entries ...cache.Entry[V]) {
+	if err := a.cache.Set(ctx, entries...); err != nil {
+		a.logger.cacheError(ctx, "cache set failed", err, "keys", len(entries))
 	}
-	_ = a.cache.Set(ctx, a.newEntry(a.buildKey(key), value))
-	return value, nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"testing"
 )
 
@@ -35,13 +36,13 @@ func TestGetCacheFirst(t *testing.T) {
 	fail = false
 	// source
 	v, _ = fetcher.Get(ctx, 123)
-	if v != "123" || fetcher.(*anyCache[int, string]).source != 2 || !maps.Equal(mapCache.Map, map[string]string{"123": "123"}) {
-		t.Fatalf("unexpected value: %s, source: %d, mapCache: %v", v, fetcher.(*anyCache[int, string]).source, mapCache.Map)
+	if v != "123" || fetcher.Stats().Loads != 2 || !maps.Equal(mapCache.Map, map[string]string{"123": "123"}) {
+		t.Fatalf("unexpected value: %s, source: %d, mapCache: %v", v, fetcher.Stats().Loads, mapCache.Map)
 	}
 	// cache
 	v, _ = fetcher.Get(ctx, 123)
-	if v != "123" || fetcher.(*anyCache[int, string]).source != 2 {
-		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if v != "123" || fetcher.Stats().Loads != 2 {
+		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.Stats().Loads)
 	}
 }
 
@@ -61,8 +62,8 @@ func TestGetCacheOnly(t *testing.T) {
 		}).Build()
 	// cache nil
 	v, err := fetcher.Get(ctx, 123)
-	if err == nil || fetcher.(*anyCache[int, string]).source != 0 {
-		t.Fatalf("unexpected err: %s, source: %d", err, fetcher.(*anyCache[int, string]).source)
+	if err == nil || fetcher.Stats().Loads != 0 {
+		t.Fatalf("unexpected err: %s, source: %d", err, fetcher.Stats().Loads)
 	}
 	// cache exist
 	_ = fetcher.Refresh(ctx, 123)
@@ -92,18 +93,51 @@ func TestGetSourceFirst(t *testing.T) {
 		}).Build()
 	// source
 	v, _ := fetcher.Get(ctx, 123)
-	if v != "123" || fetcher.(*anyCache[int, string]).source != 1 || !maps.Equal(mapCache.Map, map[string]string{"123": "123"}) {
-		t.Fatalf("unexpected value: %s, source: %d, mapCache: %v", v, fetcher.(*anyCache[int, string]).source, mapCache.Map)
+	if v != "123" || fetcher.Stats().Loads != 1 || !maps.Equal(mapCache.Map, map[string]string{"123": "123"}) {
+		t.Fatalf("unexpected value: %s, source: %d, mapCache: %v", v, fetcher.Stats().Loads, mapCache.Map)
 	}
 
 	v, _ = fetcher.Get(ctx, 123)
-	if v != "123" || fetcher.(*anyCache[int, string]).source != 2 {
-		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if v != "123" || fetcher.Stats().Loads != 2 {
+		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.Stats().Loads)
 	}
 	// source failed then cache
 	fail = true
 	v, err := fetcher.Get(ctx, 123)
-	if err != nil && v != "123" || fetcher.(*anyCache[int, string]).source != 3 {
-		t.Fatalf("unexpected err: %s, value: %s, source: %d", err, v, fetcher.(*anyCache[int, string]).source)
+	if err != nil && v != "123" || fetcher.Stats().Loads != 3 {
+		t.Fatalf("unexpected err: %s, value: %s, source: %d", err, v, fetcher.Stats().Loads)
+	}
+}
+
+func TestGetCacheError(t *testing.T) {
+	mapCache := NewMapCache[string]()
+	builder := New[int, string](mapCache).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		})
+	mapCache.Fail = true
+	// fallthrough
+	fetcher := builder.Build()
+	v, err := fetcher.Get(ctx, 123)
+	if err != nil || v != "123" || fetcher.Stats().Loads != 1 {
+		t.Fatalf("unexpected err: %v, value: %s, source: %d", err, v, fetcher.Stats().Loads)
+	}
+	values, err := fetcher.MGet(ctx, []int{123, 456})
+	if err != nil || !slices.Equal(values, []string{"123", "456"}) {
+		t.Fatalf("unexpected err: %v, values: %v", err, values)
+	}
+	// surface
+	fetcher = builder.WithCacheErrorMode(CacheErrorSurface).Build()
+	fetcher.ResetStats()
+	if _, err = fetcher.Get(ctx, 123); err == nil || fetcher.Stats().Loads != 0 {
+		t.Fatalf("unexpected err: %v, source: %d", err, fetcher.Stats().Loads)
+	}
+	if _, err = fetcher.MGet(ctx, []int{123, 456}); err == nil || fetcher.Stats().Loads != 0 {
+		t.Fatalf("unexpected err: %v, source: %d", err, fetcher.Stats().Loads)
+	}
+	// a miss still loads
+	mapCache.Fail = false
+	if v, err = fetcher.Get(ctx, 789); err != nil || v != "789" || fetcher.Stats().Loads != 1 {
+		t.Fatalf("unexpected err: %v, value: %s, source: %d", err, v, fetcher.Stats().Loads)
 	}
 }
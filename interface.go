@@ -2,15 +2,39 @@ package anycache
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
 )
 
+// ErrNotFound is returned by loaders for keys the source does not have. With
+// negative caching it is cached and returned for such keys without loading.
+var ErrNotFound = errors.New("anycache: not found")
+
 type Fetcher[K any, V any] interface {
 	Get(ctx context.Context, key K) (V, error)
+	// GetWithMeta is Get reporting where the value comes from, its age and
+	// remaining TTL along with it.
+	GetWithMeta(ctx context.Context, key K) Result[V]
 	MGet(ctx context.Context, keys []K) ([]V, error)
+	// MGetWithMeta is MGet reporting a Result per key, whose Err holds the
+	// error of the key.
+	MGetWithMeta(ctx context.Context, keys []K) ([]Result[V], error)
 	Set(ctx context.Context, key K, value V) error
 	MSet(ctx context.Context, keys []K, values []V) error
 	Del(ctx context.Context, keys ...K) error
 	Refresh(ctx context.Context, keys ...K) error
+	// Close stops the background work of the fetcher.
+	Close() error
+	StatsProvider
+}
+
+// Filter records the cache keys known to exist. Contains may report false
+// positives but never false negatives.
+type Filter interface {
+	Add(key string)
+	Contains(key string) bool
 }
 
 type Loader[K any, V any] interface {
@@ -23,6 +47,22 @@ func (l loadFunc[K, V]) Load(ctx context.Context, key K) (V, error) {
 	return l(ctx, key)
 }
 
+// NoExpiration returned as a TTL by loaders stores the value without
+// expiration, regardless of the fetcher expiration.
+const NoExpiration time.Duration = -1
+
+// TTLLoader is a Loader choosing the lifetime of every value it loads. A zero
+// TTL keeps the fetcher expiration.
+type TTLLoader[K any, V any] interface {
+	LoadWithTTL(ctx context.Context, key K) (V, time.Duration, error)
+}
+
+type ttlLoadFunc[K any, V any] func(ctx context.Context, key K) (V, time.Duration, error)
+
+func (l ttlLoadFunc[K, V]) LoadWithTTL(ctx context.Context, key K) (V, time.Duration, error) {
+	return l(ctx, key)
+}
+
 type BatchLoader[K any, V any] interface {
 	BatchLoad(ctx context.Context, keys []K) ([]V, error)
 }
@@ -32,3 +72,79 @@ type batchLoadFunc[K any, V any] func(ctx context.Context, keys []K) ([]V, error
 func (b batchLoadFunc[K, V]) BatchLoad(ctx context.Context, keys []K) ([]V, error) {
 	return b(ctx, keys)
 }
+
+// ResolvedFrom tells where the value of a Result comes from. Unlike
+// cache.Origin, which records what produced a cached value, it tells whether
+// the value was read from the cache or loaded for this call.
+type ResolvedFrom int
+
+const (
+	// FromEmpty marks the empty value of a key that could not be resolved.
+	FromEmpty ResolvedFrom = iota
+	FromCache
+	FromSource
+)
+
+func (f ResolvedFrom) String() string {
+	switch f {
+	case FromCache:
+		return "cache"
+	case FromSource:
+		return "source"
+	default:
+		return "empty"
+	}
+}
+
+// Result is the outcome of fetching one key. Found reports whether Value was
+// resolved from the cache or the source, Err holds the load error of a key
+// that could not be resolved. Stale reports that Value expired and is served
+// because reloading it failed. Metadata is the one of the entry Value was read
+// from or stored in.
+//
+// Age is the time since Value was stored. HasTTL reports that Value expires,
+// TTL being its remaining lifetime, negative once stale. Values read from a
+// cache that does not keep metadata have no known storage or expiration time:
+// their Age and TTL are zero and HasTTL is false, like for values that never
+// expire.
+type Result[V any] struct {
+	Value    V
+	Found    bool
+	From     ResolvedFrom
+	Stale    bool
+	Err      error
+	Metadata cache.Metadata
+	Age      time.Duration
+	TTL      time.Duration
+	HasTTL   bool
+}
+
+// withTimes sets the age and TTL of r as of now.
+func (r *Result[V]) withTimes(now time.Time) {
+	if r.Metadata.StoredAt.IsZero() {
+		return
+	}
+	r.Age = now.Sub(r.Metadata.StoredAt)
+	if r.Found && !r.Metadata.ExpiresAt.IsZero() {
+		r.TTL, r.HasTTL = r.Metadata.ExpiresAt.Sub(now), true
+	}
+}
+
+// LoadResult is the outcome of loading one key, a non nil Err marks the key
+// as failed without failing the rest of the batch. TTL overrides the fetcher
+// expiration like the one returned by a TTLLoader.
+type LoadResult[V any] struct {
+	Value V
+	TTL   time.Duration
+	Err   error
+}
+
+type ResultBatchLoader[K any, V any] interface {
+	BatchLoadResults(ctx context.Context, keys []K) ([]LoadResult[V], error)
+}
+
+type resultBatchLoadFunc[K any, V any] func(ctx context.Context, keys []K) ([]LoadResult[V], error)
+
+func (r resultBatchLoadFunc[K, V]) BatchLoadResults(ctx context.Context, keys []K) ([]LoadResult[V], error) {
+	return r(ctx, keys)
+}
@@ -0,0 +1,144 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("pool: closed")
+
+// Conn is a pooled connection with buffered reader and writer.
+type Conn struct {
+	net.Conn
+	R *bufio.Reader
+	W *bufio.Writer
+}
+
+type Config struct {
+	Network     string
+	Addr        string
+	Size        int
+	DialTimeout time.Duration
+	IOTimeout   time.Duration
+	// Init runs once on every new connection, e.g. to authenticate.
+	Init func(conn *Conn) error
+}
+
+// Pool bounds the number of open connections to Size and keeps the idle ones
+// for reuse.
+type Pool struct {
+	cfg    Config
+	sem    chan struct{}
+	mu     sync.Mutex
+	idle   []*Conn
+	closed bool
+}
+
+func New(cfg Config) *Pool {
+	if cfg.Size <= 0 {
+		panic("pool size must be positive")
+	}
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	return &Pool{
+		cfg: cfg,
+		sem: make(chan struct{}, cfg.Size),
+	}
+}
+
+// Get returns an idle connection or dials a new one, waiting for a free slot
+// until ctx is done. The deadline of the connection is set from ctx or the
+// configured IOTimeout.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	conn, err := p.get(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok && p.cfg.IOTimeout > 0 {
+		deadline = time.Now().Add(p.cfg.IOTimeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		p.Put(conn, err)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Put releases conn, which is closed instead of reused when err is not nil as
+// its stream may be left in an unknown state.
+func (p *Pool) Put(conn *Conn, err error) {
+	defer func() { <-p.sem }()
+	p.mu.Lock()
+	if err != nil || p.closed {
+		p.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	p.idle = append(p.idle, conn)
+	p.mu.Unlock()
+}
+
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var err error
+	for _, conn := range p.idle {
+		err = errors.Join(err, conn.Close())
+	}
+	p.idle = nil
+	return err
+}
+
+func (p *Pool) get(ctx context.Context) (*Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	dialer := net.Dialer{Timeout: p.cfg.DialTimeout}
+	nc, err := dialer.DialContext(ctx, p.cfg.Network, p.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &Conn{
+		Conn: nc,
+		R:    bufio.NewReader(nc),
+		W:    bufio.NewWriter(nc),
+	}
+	if p.cfg.Init != nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			err = nc.SetDeadline(deadline)
+		} else if p.cfg.IOTimeout > 0 {
+			err = nc.SetDeadline(time.Now().Add(p.cfg.IOTimeout))
+		}
+		if err == nil {
+			err = p.cfg.Init(conn)
+		}
+		if err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,48 @@
+package anycache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RandSource provides the random numbers used to jitter expirations, it is
+// satisfied by *rand.Rand.
+type RandSource interface {
+	Float64() float64
+}
+
+type globalRandSource struct{}
+
+func (globalRandSource) Float64() float64 {
+	return rand.Float64()
+}
+
+// lockedRandSource serializes access to sources not safe for concurrent use.
+type lockedRandSource struct {
+	mu     sync.Mutex
+	source RandSource
+}
+
+func (l *lockedRandSource) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.source.Float64()
+}
+
+// jitter spreads expiration uniformly by up to the configured percent or range
+// in either direction, never going below a millisecond.
+func (a *anyCache[K, V]) jitter(expiration time.Duration) time.Duration {
+	if expiration <= 0 {
+		return expiration
+	}
+	spread := a.jitterRange
+	if a.jitterPercent > 0 {
+		spread = time.Duration(float64(expiration) * a.jitterPercent / 100)
+	}
+	if spread <= 0 {
+		return expiration
+	}
+	offset := time.Duration((a.randSource.Float64()*2 - 1) * float64(spread))
+	return max(expiration+offset, time.Millisecond)
+}
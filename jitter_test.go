@@ -0,0 +1,75 @@
+package anycache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+type expirationCache struct {
+	*MapCache[string]
+	expirations map[string]time.Duration
+}
+
+func (e *expirationCache) Set(ctx context.Context, entries ...cache.Entry[string]) error {
+	for _, entry := range entries {
+		e.expirations[entry.Key()] = entry.Expiration()
+	}
+	return e.MapCache.Set(ctx, entries...)
+}
+
+func TestExpirationJitter(t *testing.T) {
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+	newFetcher := func() (IAnyCache[int, string], *expirationCache) {
+		c := &expirationCache{MapCache: NewMapCache[string](), expirations: map[string]time.Duration{}}
+		return New[int, string](c).
+			WithExpiration(time.Minute).
+			WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+				values := make([]string, len(keys))
+				for i, k := range keys {
+					values[i] = fmt.Sprint(k)
+				}
+				return values, nil
+			}), c
+	}
+
+	// percent
+	builder, c := newFetcher()
+	_ = builder.WithExpirationJitterPercent(10).WithRandSource(rand.New(rand.NewSource(1))).Build().Refresh(ctx, keys...)
+	distinct := map[time.Duration]bool{}
+	for _, expiration := range c.expirations {
+		if expiration < 54*time.Second || expiration > 66*time.Second {
+			t.Fatalf("expiration out of range: %s", expiration)
+		}
+		distinct[expiration] = true
+	}
+	if len(distinct) < 90 {
+		t.Fatalf("expirations not spread: %d distinct", len(distinct))
+	}
+
+	// range, deterministic with the same seed
+	builder, c = newFetcher()
+	_ = builder.WithExpirationJitterRange(time.Second).WithRandSource(rand.New(rand.NewSource(1))).Build().Refresh(ctx, keys...)
+	first := c.expirations
+	builder, c = newFetcher()
+	_ = builder.WithExpirationJitterRange(time.Second).WithRandSource(rand.New(rand.NewSource(1))).Build().Refresh(ctx, keys...)
+	for key, expiration := range first {
+		if expiration < 59*time.Second || expiration > 61*time.Second || c.expirations[key] != expiration {
+			t.Fatalf("unexpected expiration of %s: %s, %s", key, expiration, c.expirations[key])
+		}
+	}
+
+	// no expiration is left untouched
+	builder, c = newFetcher()
+	_ = builder.WithExpiration(0).WithExpirationJitterPercent(50).Build().Set(ctx, 1, "1")
+	if c.expirations["1"] != 0 {
+		t.Fatalf("unexpected expiration: %s", c.expirations["1"])
+	}
+}
@@ -0,0 +1,118 @@
+package anycache
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogOptions configures the logging of a fetcher, zero fields take their
+// defaults.
+type LogOptions struct {
+	// CacheErrorLevel is the level of the cache errors not returned to the
+	// caller, slog.LevelWarn by default.
+	CacheErrorLevel slog.Leveler
+	// LoadErrorLevel is the level of the loader failures, slog.LevelWarn by
+	// default.
+	LoadErrorLevel slog.Leveler
+	// FallbackLevel is the level of the strategy fallbacks, slog.LevelInfo by
+	// default.
+	FallbackLevel slog.Leveler
+	// Interval is the minimum time between two records of the same message, 1s
+	// by default and negative to log every record. The number of records
+	// suppressed meanwhile is attached to the next one.
+	Interval time.Duration
+}
+
+// WithLogger logs the errors the fetcher does not return, the loader failures
+// and the strategy fallbacks to logger.
+func (a *anyCache[K, V]) WithLogger(logger *slog.Logger) IAnyCache[K, V] {
+	a.slogger = logger
+	return a
+}
+
+func (a *anyCache[K, V]) WithLogOptions(options LogOptions) IAnyCache[K, V] {
+	a.logOptions = options
+	return a
+}
+
+type logState struct {
+	last       time.Time
+	suppressed int
+}
+
+// logger rate limits records per message, a nil logger discards them.
+type logger struct {
+	logger  *slog.Logger
+	options LogOptions
+
+	mu     sync.Mutex
+	states map[string]*logState
+}
+
+func newLogger(l *slog.Logger, options LogOptions) *logger {
+	if options.CacheErrorLevel == nil {
+		options.CacheErrorLevel = slog.LevelWarn
+	}
+	if options.LoadErrorLevel == nil {
+		options.LoadErrorLevel = slog.LevelWarn
+	}
+	if options.FallbackLevel == nil {
+		options.FallbackLevel = slog.LevelInfo
+	}
+	if options.Interval == 0 {
+		options.Interval = time.Second
+	}
+	return &logger{
+		logger:  l,
+		options: options,
+		states:  make(map[string]*logState),
+	}
+}
+
+func (l *logger) cacheError(ctx context.Context, msg string, err error, args ...any) {
+	if l != nil {
+		l.log(ctx, l.options.CacheErrorLevel.Level(), msg, append(args, "error", err)...)
+	}
+}
+
+func (l *logger) loadError(ctx context.Context, msg string, err error, args ...any) {
+	if l != nil {
+		l.log(ctx, l.options.LoadErrorLevel.Level(), msg, append(args, "error", err)...)
+	}
+}
+
+func (l *logger) fallback(ctx context.Context, msg string, args ...any) {
+	if l != nil {
+		l.log(ctx, l.options.FallbackLevel.Level(), msg, args...)
+	}
+}
+
+func (l *logger) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	if l.options.Interval > 0 {
+		now := time.Now()
+		l.mu.Lock()
+		state, ok := l.states[msg]
+		if !ok {
+			state = &logState{}
+			l.states[msg] = state
+		}
+		if now.Sub(state.last) < l.options.Interval {
+			state.suppressed++
+			l.mu.Unlock()
+			return
+		}
+		state.last = now
+		suppressed := state.suppressed
+		state.suppressed = 0
+		l.mu.Unlock()
+		if suppressed > 0 {
+			args = append(args, "suppressed", suppressed)
+		}
+	}
+	l.logger.Log(ctx, level, msg, args...)
+}
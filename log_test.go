@@ -0,0 +1,121 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *recordingHandler) find(msg string) []slog.Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var records []slog.Record
+	for _, r := range h.records {
+		if r.Message == msg {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+func attr(r slog.Record, key string) slog.Value {
+	var value slog.Value
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == key {
+			value = a.Value
+			return false
+		}
+		return true
+	})
+	return value
+}
+
+func TestLogger(t *testing.T) {
+	handler := &recordingHandler{}
+	mapCache := NewMapCache[string]()
+	mapCache.Fail = true
+	fetcher := New[int, string](mapCache).
+		WithLogger(slog.New(handler)).
+		WithLogOptions(LogOptions{LoadErrorLevel: slog.LevelError, Interval: 20 * time.Millisecond}).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			if key == 78 {
+				return "", errors.New("error")
+			}
+			return fmt.Sprint(key), nil
+		}).Build()
+
+	_, _ = fetcher.Get(ctx, 123)
+	if records := handler.find("cache get failed, loading from the source"); len(records) != 1 || records[0].Level != slog.LevelInfo {
+		t.Fatalf("unexpected records: %v", records)
+	}
+	if records := handler.find("cache set failed"); len(records) != 1 || records[0].Level != slog.LevelWarn {
+		t.Fatalf("unexpected records: %v", records)
+	}
+
+	mapCache.Fail = false
+	_, _ = fetcher.Get(ctx, 78)
+	_, _ = fetcher.Get(ctx, 78)
+	records := handler.find("load failed")
+	if len(records) != 1 || records[0].Level != slog.LevelError || attr(records[0], "key").String() != "78" {
+		t.Fatalf("unexpected records: %v", records)
+	}
+	time.Sleep(30 * time.Millisecond)
+	_, _ = fetcher.Get(ctx, 78)
+	if records = handler.find("load failed"); len(records) != 2 || attr(records[1], "suppressed").Int64() != 1 {
+		t.Fatalf("unexpected records: %v", records)
+	}
+
+	_, _ = fetcher.MGet(ctx, []int{78, 456})
+	if records = handler.find("keys failed to load"); len(records) != 1 || attr(records[0], "failed").Int64() != 1 {
+		t.Fatalf("unexpected records: %v", records)
+	}
+}
+
+func TestLoggerBatchLength(t *testing.T) {
+	handler := &recordingHandler{}
+	fetcher := New[int, string](NewMapCache[string]()).
+		WithLogger(slog.New(handler)).
+		WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+			return []string{"1"}, nil
+		}).Build()
+	_, _ = fetcher.MGet(ctx, []int{1, 2})
+	if len(handler.records) != 1 {
+		t.Fatalf("unexpected records: %v", handler.records)
+	}
+	record := handler.records[0]
+	if record.Message != "batch load failed" || attr(record, "keys").Int64() != 2 || attr(record, "error").String() != "keys and results length not equal: 1 results for 2 keys" {
+		t.Fatalf("unexpected record: %v", record)
+	}
+
+	handler = &recordingHandler{}
+	fetcher = New[int, string](NewMapCache[string]()).
+		WithLogger(slog.New(handler)).
+		WithResultBatchLoadFunc(func(ctx context.Context, keys []int) ([]LoadResult[string], error) {
+			return make([]LoadResult[string], 1), nil
+		}).Build()
+	_, _ = fetcher.MGet(ctx, []int{1, 2})
+	if len(handler.records) != 1 || handler.records[0].Message != "batch load failed" {
+		t.Fatalf("unexpected records: %v", handler.records)
+	}
+}
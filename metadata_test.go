@@ -0,0 +1,109 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+	"github.com/xianlianghe0123/anycache/codec"
+)
+
+func TestGetWithMeta(t *testing.T) {
+	bytesCache := memory.New[[]byte]()
+	fetcher := New[int, string](cache.NewCodecCacher[string](bytesCache, codec.String{}, cache.WithMetadata())).
+		WithExpiration(time.Minute).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}).Build()
+
+	start := time.Now()
+	loaded := fetcher.GetWithMeta(ctx, 1)
+	if loaded.Err != nil || loaded.Value != "1" || loaded.Metadata.Origin != cache.OriginLoader || loaded.Metadata.StoredAt.Before(start) {
+		t.Fatalf("unexpected result: %+v", loaded)
+	}
+	// the metadata is read back from the encoded entry
+	cached := fetcher.GetWithMeta(ctx, 1)
+	if cached.Err != nil || cached.Metadata.Version != loaded.Metadata.Version || !cached.Metadata.StoredAt.Equal(loaded.Metadata.StoredAt) {
+		t.Fatalf("unexpected result: %+v, loaded: %+v", cached, loaded)
+	}
+
+	_ = fetcher.Set(ctx, 1, "set")
+	set := fetcher.GetWithMeta(ctx, 1)
+	if set.Value != "set" || set.Metadata.Origin != cache.OriginSet || set.Metadata.Version <= loaded.Metadata.Version {
+		t.Fatalf("unexpected result: %+v", set)
+	}
+
+	_ = fetcher.Refresh(ctx, 1)
+	refreshed := fetcher.GetWithMeta(ctx, 1)
+	if refreshed.Value != "1" || refreshed.Metadata.Origin != cache.OriginRefresh || refreshed.Metadata.Version <= set.Metadata.Version {
+		t.Fatalf("unexpected result: %+v", refreshed)
+	}
+}
+
+func TestGetWithMetaTimes(t *testing.T) {
+	newFetcher := func(c cache.Cacher[string]) Fetcher[int, string] {
+		return New[int, string](c).
+			WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+				return fmt.Sprint(key), nil
+			}).Build()
+	}
+
+	// never expiring
+	fetcher := newFetcher(memory.New[string]())
+	_, _ = fetcher.Get(ctx, 1)
+	if r := fetcher.GetWithMeta(ctx, 1); r.From != FromCache || r.HasTTL || r.TTL != 0 || r.Age <= 0 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	// no metadata kept
+	fetcher = newFetcher(NewMapCache[string]())
+	_, _ = fetcher.Get(ctx, 1)
+	if r := fetcher.GetWithMeta(ctx, 1); r.From != FromCache || r.HasTTL || r.TTL != 0 || r.Age != 0 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
+
+func TestGetWithMetaResolvedFrom(t *testing.T) {
+	var failing atomic.Bool
+	c := memory.New[string]()
+	newFetcher := func(strategy strategy) Fetcher[int, string] {
+		return New[int, string](c).
+			WithStrategy(strategy).
+			WithExpiration(20 * time.Millisecond).
+			WithStaleIfError(time.Minute).
+			WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+				if failing.Load() {
+					return "", errors.New("load failed")
+				}
+				return fmt.Sprint(key), nil
+			}).Build()
+	}
+	fetcher := newFetcher(StrategyCacheFirst)
+
+	if r := fetcher.GetWithMeta(ctx, 1); r.From != FromSource || !r.HasTTL || r.TTL <= 0 || r.TTL > 20*time.Millisecond {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	results, _ := fetcher.MGetWithMeta(ctx, []int{1, 2})
+	if results[0].From != FromCache || results[0].Age <= 0 || results[1].From != FromSource {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if r := newFetcher(StrategySourceFirst).GetWithMeta(ctx, 1); r.From != FromSource {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	if r := newFetcher(StrategyCacheOnly).GetWithMeta(ctx, 3); r.From != FromEmpty || r.Found {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	failing.Store(true)
+	if r := fetcher.GetWithMeta(ctx, 1); r.From != FromCache || !r.Stale || !r.HasTTL || r.TTL >= 0 || r.Age < 30*time.Millisecond {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	if r := fetcher.GetWithMeta(ctx, 3); r.From != FromEmpty || r.Err == nil {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xianlianghe0123/anycache"
+)
+
+var ErrRegistered = errors.New("metrics: namespace already registered")
+
+// Registry exports the stats of the fetchers registered in it in the
+// Prometheus text exposition format, every sample labelled with the namespace
+// of its fetcher. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	prefix   string
+	fetchers map[string]anycache.StatsProvider
+}
+
+type Option func(r *Registry)
+
+// WithPrefix sets the prefix of the metric names, anycache by default.
+func WithPrefix(prefix string) Option {
+	return func(r *Registry) {
+		r.prefix = prefix
+	}
+}
+
+func New(opts ...Option) *Registry {
+	r := &Registry{
+		prefix:   "anycache",
+		fetchers: make(map[string]anycache.StatsProvider),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Registry) Register(namespace string, fetcher anycache.StatsProvider) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.fetchers[namespace]; ok {
+		return fmt.Errorf("%w: %q", ErrRegistered, namespace)
+	}
+	r.fetchers[namespace] = fetcher
+	return nil
+}
+
+func (r *Registry) Unregister(namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fetchers, namespace)
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+type namespaceStats struct {
+	namespace string
+	stats     anycache.Stats
+}
+
+type counter struct {
+	name  string
+	help  string
+	value func(s anycache.Stats) int64
+}
+
+var counters = []counter{
+	{"hits_total", "Cache lookups answered by the cache.", func(s anycache.Stats) int64 { return s.Hits }},
+	{"misses_total", "Cache lookups missing the cache.", func(s anycache.Stats) int64 { return s.Misses }},
+	{"loads_total", "Keys loaded from the source by reads.", func(s anycache.Stats) int64 { return s.Loads }},
+	{"load_errors_total", "Keys failing to load for reads.", func(s anycache.Stats) int64 { return s.LoadErrors }},
+	{"loader_calls_total", "Load and BatchLoad calls.", func(s anycache.Stats) int64 { return s.LoaderCalls }},
+	{"cache_errors_total", "Failed cache operations.", func(s anycache.Stats) int64 { return s.CacheErrors }},
+	{"sets_total", "Entries written to the cache.", func(s anycache.Stats) int64 { return s.Sets }},
+	{"deletes_total", "Keys deleted from the cache.", func(s anycache.Stats) int64 { return s.Deletes }},
+	{"refreshes_total", "Reloads of cached keys.", func(s anycache.Stats) int64 { return s.Refreshes }},
+	{"refreshed_keys_total", "Keys reloaded by refreshes.", func(s anycache.Stats) int64 { return s.RefreshedKeys }},
+	{"refresh_errors_total", "Refreshes failing for any key.", func(s anycache.Stats) int64 { return s.RefreshErrors }},
+}
+
+// WriteTo writes the metrics of the registered fetchers to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	all := make([]namespaceStats, 0, len(r.fetchers))
+	for namespace, fetcher := range r.fetchers {
+		all = append(all, namespaceStats{namespace: namespace, stats: fetcher.Stats()})
+	}
+	r.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].namespace < all[j].namespace
+	})
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, c := range counters {
+		r.header(cw, c.name, c.help, "counter")
+		for _, ns := range all {
+			fmt.Fprintf(cw, "%s_%s{namespace=%s} %d\n", r.prefix, c.name, quote(ns.namespace), c.value(ns.stats))
+		}
+	}
+	r.header(cw, "hit_ratio", "Ratio of hits to cache lookups.", "gauge")
+	for _, ns := range all {
+		fmt.Fprintf(cw, "%s_hit_ratio{namespace=%s} %s\n", r.prefix, quote(ns.namespace), formatFloat(ns.stats.HitRatio()))
+	}
+	r.header(cw, "load_duration_seconds", "Durations of Load and BatchLoad calls.", "histogram")
+	for _, ns := range all {
+		r.durations(cw, "load_duration_seconds", labels(ns.namespace), ns.stats.LoadLatency)
+	}
+	r.header(cw, "batch_size", "Keys of BatchLoad calls.", "histogram")
+	for _, ns := range all {
+		r.sizes(cw, "batch_size", labels(ns.namespace), ns.stats.BatchSizes)
+	}
+	r.header(cw, "cache_operation_duration_seconds", "Durations of cache operations.", "histogram")
+	for _, ns := range all {
+		latency := ns.stats.CacheLatency
+		for _, op := range []struct {
+			name string
+			h    anycache.Histogram
+		}{
+			{"get", latency.Get},
+			{"mget", latency.MGet},
+			{"set", latency.Set},
+			{"del", latency.Del},
+		} {
+			r.durations(cw, "cache_operation_duration_seconds", labels(ns.namespace, "op", op.name), op.h)
+		}
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func (r *Registry) header(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", r.prefix, name, help, r.prefix, name, typ)
+}
+
+func (r *Registry) durations(w io.Writer, name, labels string, h anycache.Histogram) {
+	bounds := make([]string, len(h.Bounds))
+	for i, bound := range h.Bounds {
+		bounds[i] = formatFloat(bound.Seconds())
+	}
+	r.histogram(w, name, labels, bounds, h.Counts, h.Count, formatFloat(h.Sum.Seconds()))
+}
+
+func (r *Registry) sizes(w io.Writer, name, labels string, h anycache.SizeHistogram) {
+	bounds := make([]string, len(h.Bounds))
+	for i, bound := range h.Bounds {
+		bounds[i] = strconv.FormatInt(bound, 10)
+	}
+	r.histogram(w, name, labels, bounds, h.Counts, h.Count, strconv.FormatInt(h.Sum, 10))
+}
+
+// histogram writes cumulative buckets from the per bucket counts.
+func (r *Registry) histogram(w io.Writer, name, labels string, bounds []string, counts []int64, count int64, sum string) {
+	var cumulative int64
+	for i, bound := range bounds {
+		if i < len(counts) {
+			cumulative += counts[i]
+		}
+		fmt.Fprintf(w, "%s_%s_bucket{%s,le=%q} %d\n", r.prefix, name, labels, bound, cumulative)
+	}
+	fmt.Fprintf(w, "%s_%s_bucket{%s,le=\"+Inf\"} %d\n", r.prefix, name, labels, count)
+	fmt.Fprintf(w, "%s_%s_sum{%s} %s\n", r.prefix, name, labels, sum)
+	fmt.Fprintf(w, "%s_%s_count{%s} %d\n", r.prefix, name, labels, count)
+}
+
+// labels formats the namespace label followed by the name value pairs of kv.
+func labels(namespace string, kv ...string) string {
+	var b strings.Builder
+	b.WriteString("namespace=")
+	b.WriteString(quote(namespace))
+	for i := 0; i+1 < len(kv); i += 2 {
+		b.WriteString(",")
+		b.WriteString(kv[i])
+		b.WriteString("=")
+		b.WriteString(quote(kv[i+1]))
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xianlianghe0123/anycache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+var ctx = context.Background()
+
+func newFetcher() anycache.Fetcher[int, string] {
+	return anycache.New[int, string](memory.New[string]()).
+		WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				values[i] = fmt.Sprint(key)
+			}
+			return values, nil
+		}).Build()
+}
+
+func TestRegistry(t *testing.T) {
+	fetcher := newFetcher()
+	r := New()
+	if err := r.Register("users", fetcher); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := r.Register("users", fetcher); !errors.Is(err, ErrRegistered) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := r.Register(`a"b`, newFetcher()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	_, _ = fetcher.MGet(ctx, []int{1, 2, 3})
+	_, _ = fetcher.Get(ctx, 1)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+	body, _ := io.ReadAll(w.Body)
+	for _, line := range []string{
+		"# TYPE anycache_hits_total counter",
+		`anycache_hits_total{namespace="users"} 1`,
+		`anycache_misses_total{namespace="users"} 3`,
+		`anycache_loader_calls_total{namespace="users"} 1`,
+		`anycache_hits_total{namespace="a\"b"} 0`,
+		`anycache_hit_ratio{namespace="users"} 0.25`,
+		"# TYPE anycache_batch_size histogram",
+		`anycache_batch_size_bucket{namespace="users",le="2"} 0`,
+		`anycache_batch_size_bucket{namespace="users",le="5"} 1`,
+		`anycache_batch_size_bucket{namespace="users",le="+Inf"} 1`,
+		`anycache_batch_size_sum{namespace="users"} 3`,
+		`anycache_load_duration_seconds_count{namespace="users"} 1`,
+		`anycache_cache_operation_duration_seconds_count{namespace="users",op="get"} 1`,
+		`anycache_cache_operation_duration_seconds_count{namespace="users",op="set"} 1`,
+		`anycache_cache_operation_duration_seconds_bucket{namespace="users",op="mget",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Fatalf("missing %s in:\n%s", line, body)
+		}
+	}
+
+	r.Unregister("users")
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil || strings.Contains(b.String(), "users") {
+		t.Fatalf("unexpected err: %v, metrics:\n%s", err, b.String())
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	r := New(WithPrefix("app_cache"))
+	_ = r.Register("users", newFetcher())
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil || n != int64(b.Len()) || !strings.Contains(b.String(), `app_cache_hits_total{namespace="users"} 0`) {
+		t.Fatalf("unexpected n: %d, err: %v, metrics:\n%s", n, err, b.String())
+	}
+}
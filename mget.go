@@ -2,16 +2,61 @@ package anycache
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
 )
 
 func (a *anyCache[K, V]) mGet(ctx context.Context, keys []K) ([]V, error) {
+	results, err := a.mGetResults(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]V, len(results))
+	for i := range results {
+		values[i] = results[i].Value
+	}
+	return values, nil
+}
+
+func (a *anyCache[K, V]) mGetResults(ctx context.Context, keys []K) ([]Result[V], error) {
+	if a.filter == nil {
+		return a.mGetStrategy(ctx, keys)
+	}
+	results := make([]Result[V], len(keys))
+	var passedKeyIndices []int
+	for i, key := range keys {
+		if a.filtered(key) {
+			results[i] = Result[V]{Value: a.emptyValue, Err: ErrNotFound}
+		} else {
+			passedKeyIndices = append(passedKeyIndices, i)
+		}
+	}
+	if len(passedKeyIndices) == 0 {
+		return results, nil
+	}
+	passedKeys := make([]K, len(passedKeyIndices))
+	for i := range passedKeyIndices {
+		passedKeys[i] = keys[passedKeyIndices[i]]
+	}
+	passed, err := a.mGetStrategy(ctx, passedKeys)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range passed {
+		results[passedKeyIndices[i]] = result
+	}
+	return results, nil
+}
+
+func (a *anyCache[K, V]) mGetStrategy(ctx context.Context, keys []K) ([]Result[V], error) {
 	switch a.strategy {
 	case StrategySourceFirst:
 		return a.mGetSourceFirst(ctx, keys)
 	case StrategyCacheOnly:
-		_, values, err := a.mGetCache(ctx, keys)
-		return values, err
+		return a.mGetCacheOnly(ctx, keys)
 	case StrategyCacheFirst:
 		fallthrough
 	default:
@@ -19,67 +64,162 @@ func (a *anyCache[K, V]) mGet(ctx context.Context, keys []K) ([]V, error) {
 	}
 }
 
-func (a *anyCache[K, V]) mGetCacheFirst(ctx context.Context, keys []K) ([]V, error) {
-	missKeyIndices, result, err := a.mGetCache(ctx, keys)
+func (a *anyCache[K, V]) mGetCacheFirst(ctx context.Context, keys []K) ([]Result[V], error) {
+	missKeyIndices, results, err := a.mGetCache(ctx, keys)
 	if err != nil {
-		result = make([]V, len(keys))
+		if a.cacheErrorMode == CacheErrorSurface {
+			return nil, err
+		}
+		a.logger.fallback(ctx, "cache mget failed, loading from the source", "keys", len(keys), "error", err)
+		results = make([]Result[V], len(keys))
 		missKeyIndices = make([]int, len(keys))
 		for i := range missKeyIndices {
 			missKeyIndices[i] = i
 		}
 	}
 	if len(missKeyIndices) == 0 {
-		return result, nil
+		return results, nil
 	}
 
 	missKeys := make([]K, len(missKeyIndices))
 	for i := range missKeyIndices {
 		missKeys[i] = keys[missKeyIndices[i]]
 	}
-	values, err := a.mGetSource(ctx, missKeys)
+	stale := 0
+	for i, result := range a.mGetSource(ctx, missKeys) {
+		// an expired value is kept when reloading it fails
+		if result.Err != nil && !errors.Is(result.Err, ErrNotFound) && results[missKeyIndices[i]].Stale {
+			stale++
+			continue
+		}
+		results[missKeyIndices[i]] = result
+	}
+	if stale > 0 {
+		a.logger.fallback(ctx, "load failed, serving stale values", "keys", stale)
+	}
+	return results, nil
+}
+
+// mGetSourceFirst falls back to the cache for the keys that failed to load,
+// except those the source reports not found.
+func (a *anyCache[K, V]) mGetSourceFirst(ctx context.Context, keys []K) ([]Result[V], error) {
+	results := a.mGetSource(ctx, keys)
+	var failedKeyIndices []int
+	for i := range results {
+		if results[i].Err != nil && !errors.Is(results[i].Err, ErrNotFound) {
+			failedKeyIndices = append(failedKeyIndices, i)
+		}
+	}
+	if len(failedKeyIndices) == 0 {
+		return results, nil
+	}
+	a.logger.fallback(ctx, "load failed, reading from the cache", "keys", len(failedKeyIndices))
+
+	failedKeys := make([]K, len(failedKeyIndices))
+	for i := range failedKeyIndices {
+		failedKeys[i] = keys[failedKeyIndices[i]]
+	}
+	_, cached, err := a.mGetCache(ctx, failedKeys)
 	if err != nil {
-		return result, nil
+		return results, err
 	}
-	for i, value := range values {
-		result[missKeyIndices[i]] = value
+	for i, result := range cached {
+		if result.Found || result.Err != nil {
+			results[failedKeyIndices[i]] = result
+		}
 	}
-	_ = a.mSet(ctx, missKeys, values)
-	return result, nil
+	return results, nil
 }
 
-func (a *anyCache[K, V]) mGetSourceFirst(ctx context.Context, keys []K) ([]V, error) {
-	values, err := a.mGetSource(ctx, keys)
-	if err == nil {
-		return values, nil
+// mGetCacheOnly reports expired values as misses.
+func (a *anyCache[K, V]) mGetCacheOnly(ctx context.Context, keys []K) ([]Result[V], error) {
+	missKeyIndices, results, err := a.mGetCache(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range missKeyIndices {
+		results[i] = Result[V]{Value: a.emptyValue, Err: cache.ErrNotFound}
 	}
-	_, values, err = a.mGetCache(ctx, keys)
-	return values, err
+	return results, nil
 }
 
-func (a *anyCache[K, V]) mGetCache(ctx context.Context, keys []K) (missKeyIndices []int, values []V, err error) {
-	entries, err := a.cache.MGet(ctx, a.buildKeys(keys))
+// mGetCache returns the indices of the keys to load along with the cached
+// results, values kept past their expiration for the stale grace period are
+// returned marked stale.
+func (a *anyCache[K, V]) mGetCache(ctx context.Context, keys []K) (missKeyIndices []int, results []Result[V], err error) {
+	cacheKeys := a.buildKeys(keys)
+	entries, err := a.cache.MGet(ctx, cacheKeys)
 	if err != nil {
 		return nil, nil, err
 	}
-	values = make([]V, len(keys))
-	for i, entry := range entries {
-		if entries[i] != nil {
-			values[i] = entry.Value()
-		} else {
-			values[i] = a.emptyValue
+	results = make([]Result[V], len(keys))
+	now := time.Now()
+	var staleKeys []K
+	for i := range keys {
+		switch {
+		case i >= len(entries) || entries[i] == nil:
+			results[i].Value = a.emptyValue
 			missKeyIndices = append(missKeyIndices, i)
+		case a.staleGrace > 0 && expired(entries[i], now):
+			results[i] = Result[V]{Value: entries[i].Value(), Found: true, From: FromCache, Stale: true, Metadata: cache.MetadataOf(entries[i])}
+			missKeyIndices = append(missKeyIndices, i)
+		default:
+			md := cache.MetadataOf(entries[i])
+			if md.NotFound {
+				results[i] = Result[V]{Value: a.emptyValue, Err: ErrNotFound, Metadata: md}
+			} else {
+				results[i] = Result[V]{Value: entries[i].Value(), Found: true, From: FromCache, Metadata: md}
+			}
+			if stale(entries[i], now) {
+				staleKeys = append(staleKeys, keys[i])
+			}
+			if a.refresher != nil {
+				a.refresher.track(cacheKeys[i], keys[i], md.ExpiresAt)
+			}
 		}
 	}
-	atomic.AddInt64(&a.hit, int64(len(keys)-len(missKeyIndices)))
-	return missKeyIndices, values, nil
+	atomic.AddInt64(&a.stats.hits, int64(len(keys)-len(missKeyIndices)))
+	atomic.AddInt64(&a.stats.misses, int64(len(missKeyIndices)))
+	if len(staleKeys) > 0 {
+		a.revalidate(ctx, staleKeys)
+	}
+	return missKeyIndices, results, nil
 }
 
-func (a *anyCache[K, V]) mGetSource(ctx context.Context, keys []K) ([]V, error) {
-	atomic.AddInt64(&a.source, int64(len(keys)))
-	values, err := a.batchLoader.BatchLoad(ctx, keys)
+// mGetSource loads keys and caches the loaded ones, a failure of the whole
+// batch is reported as the error of every key.
+func (a *anyCache[K, V]) mGetSource(ctx context.Context, keys []K) []Result[V] {
+	atomic.AddInt64(&a.stats.loads, int64(len(keys)))
+	results := make([]Result[V], len(keys))
+	loaded, err := a.batchLoad(ctx, keys)
 	if err != nil {
-		return nil, err
+		atomic.AddInt64(&a.stats.loadErrors, int64(len(keys)))
+		a.logger.loadError(ctx, "batch load failed", err, "keys", len(keys))
+		for i := range results {
+			results[i] = Result[V]{Value: a.emptyValue, Err: err}
+		}
+		return results
 	}
-	_ = a.mSet(ctx, keys, values)
-	return values, nil
+	metadata, err := a.storeLoaded(ctx, keys, loaded, cache.OriginLoader)
+	if err != nil {
+		a.logger.cacheError(ctx, "cache set failed", err, "keys", len(keys))
+	}
+	var failed int
+	var loadErr error
+	for i, result := range loaded {
+		if result.Err != nil {
+			if !errors.Is(result.Err, ErrNotFound) {
+				failed++
+				loadErr = result.Err
+			}
+			results[i] = Result[V]{Value: a.emptyValue, Err: result.Err, Metadata: metadata[i]}
+		} else {
+			results[i] = Result[V]{Value: result.Value, Found: true, From: FromSource, Metadata: metadata[i]}
+		}
+	}
+	if failed > 0 {
+		atomic.AddInt64(&a.stats.loadErrors, int64(failed))
+		a.logger.loadError(ctx, "keys failed to load", loadErr, "keys", len(keys), "failed", failed)
+	}
+	return results
 }
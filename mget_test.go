@@ -7,6 +7,8 @@ import (
 	"maps"
 	"slices"
 	"testing"
+
+	"github.com/xianlianghe0123/anycache/cache"
 )
 
 func TestMGetCacheFirst(t *testing.T) {
@@ -18,25 +20,25 @@ func TestMGetCacheFirst(t *testing.T) {
 		}).Build()
 	// source
 	v, _ := fetcher.MGet(ctx, []int{123, 456})
-	if !slices.Equal(v, []string{"123", "456"}) || fetcher.(*anyCache[int, string]).source != 2 ||
+	if !slices.Equal(v, []string{"123", "456"}) || fetcher.Stats().Loads != 2 ||
 		!maps.Equal(mapCache.Map, map[string]string{"123": "123", "456": "456"}) {
-		t.Fatalf("unexpected value: %v, source: %d, mapCache: %v", v, fetcher.(*anyCache[int, string]).source, mapCache.Map)
+		t.Fatalf("unexpected value: %v, source: %d, mapCache: %v", v, fetcher.Stats().Loads, mapCache.Map)
 	}
 	// cache
 	v, _ = fetcher.MGet(ctx, []int{123, 456})
-	if !slices.Equal(v, []string{"123", "456"}) || fetcher.(*anyCache[int, string]).source != 2 {
-		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "456"}) || fetcher.Stats().Loads != 2 {
+		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.Stats().Loads)
 	}
 	// partly
 	v, _ = fetcher.MGet(ctx, []int{123, 78, 456})
-	if !slices.Equal(v, []string{"123", "78", "456"}) || fetcher.(*anyCache[int, string]).source != 3 {
-		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "78", "456"}) || fetcher.Stats().Loads != 3 {
+		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.Stats().Loads)
 	}
 	// cache failed
 	mapCache.Fail = true
 	v, _ = fetcher.MGet(ctx, []int{123, 78, 456})
-	if !slices.Equal(v, []string{"123", "78", "456"}) || fetcher.(*anyCache[int, string]).source != 6 {
-		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "78", "456"}) || fetcher.Stats().Loads != 6 {
+		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.Stats().Loads)
 	}
 }
 
@@ -49,20 +51,28 @@ func TestMGetCacheOnly(t *testing.T) {
 		}).Build()
 	// cache nil
 	v, _ := fetcher.MGet(ctx, []int{123, 456})
-	if !slices.Equal(v, []string{"", ""}) || fetcher.(*anyCache[int, string]).source != 0 {
-		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"", ""}) || fetcher.Stats().Loads != 0 {
+		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.Stats().Loads)
 	}
 
 	_ = fetcher.Refresh(ctx, 123, 456)
 	// cache exist
 	v, _ = fetcher.MGet(ctx, []int{123, 456})
-	if !slices.Equal(v, []string{"123", "456"}) || fetcher.(*anyCache[int, string]).source != 0 {
-		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "456"}) || fetcher.Stats().Loads != 0 {
+		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.Stats().Loads)
 	}
 	// partly
 	v, _ = fetcher.MGet(ctx, []int{123, 78, 456})
-	if !slices.Equal(v, []string{"123", "", "456"}) || fetcher.(*anyCache[int, string]).source != 0 {
-		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "", "456"}) || fetcher.Stats().Loads != 0 {
+		t.Fatalf("unexpected value: %v, source: %d", v, fetcher.Stats().Loads)
+	}
+	// misses are reported like Get does
+	results, err := fetcher.MGetWithMeta(ctx, []int{123, 78})
+	if err != nil || results[0].Err != nil || !errors.Is(results[1].Err, cache.ErrNotFound) {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+	if r := fetcher.GetWithMeta(ctx, 78); !errors.Is(r.Err, cache.ErrNotFound) {
+		t.Fatalf("unexpected result: %v", r)
 	}
 }
 
@@ -82,19 +92,100 @@ func TestMGetSourceFirst(t *testing.T) {
 		}).Build()
 	// source
 	v, _ := fetcher.MGet(ctx, []int{123, 456})
-	if !slices.Equal(v, []string{"123", "456"}) || fetcher.(*anyCache[int, string]).source != 2 ||
+	if !slices.Equal(v, []string{"123", "456"}) || fetcher.Stats().Loads != 2 ||
 		!maps.Equal(mapCache.Map, map[string]string{"123": "123", "456": "456"}) {
-		t.Fatalf("unexpected value: %s, source: %d, mapCache: %v", v, fetcher.(*anyCache[int, string]).source, mapCache.Map)
+		t.Fatalf("unexpected value: %s, source: %d, mapCache: %v", v, fetcher.Stats().Loads, mapCache.Map)
 	}
 	//
 	v, _ = fetcher.MGet(ctx, []int{123, 456})
-	if !slices.Equal(v, []string{"123", "456"}) || fetcher.(*anyCache[int, string]).source != 4 {
-		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "456"}) || fetcher.Stats().Loads != 4 {
+		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.Stats().Loads)
 	}
 
 	// source failed
 	v, _ = fetcher.MGet(ctx, []int{123, 78, 456})
-	if !slices.Equal(v, []string{"123", "", "456"}) || fetcher.(*anyCache[int, string]).source != 7 {
-		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.(*anyCache[int, string]).source)
+	if !slices.Equal(v, []string{"123", "", "456"}) || fetcher.Stats().Loads != 7 {
+		t.Fatalf("unexpected value: %s, source: %d", v, fetcher.Stats().Loads)
+	}
+}
+
+func TestMGetWithMeta(t *testing.T) {
+	mapCache := NewMapCache[string]()
+	loadErr := errors.New("error")
+	fetcher := New[int, string](mapCache).
+		WithStrategy(StrategyCacheFirst).
+		WithResultBatchLoadFunc(func(ctx context.Context, keys []int) ([]LoadResult[string], error) {
+			results := make([]LoadResult[string], len(keys))
+			for i, k := range keys {
+				if k == 78 {
+					results[i].Err = loadErr
+				} else {
+					results[i].Value = fmt.Sprint(k)
+				}
+			}
+			return results, nil
+		}).Build()
+	_ = fetcher.Set(ctx, 123, "cached")
+	results, err := fetcher.MGetWithMeta(ctx, []int{123, 78, 456})
+	if err != nil || !slices.Equal(withoutMetadata(results), []Result[string]{
+		{Value: "cached", Found: true, From: FromCache},
+		{Value: "", Err: loadErr},
+		{Value: "456", Found: true, From: FromSource},
+	}) {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+	if !maps.Equal(mapCache.Map, map[string]string{"123": "cached", "456": "456"}) {
+		t.Fatalf("unexpected mapCache: %v", mapCache.Map)
+	}
+	// single load reports the key error
+	if _, err = fetcher.Get(ctx, 78); !errors.Is(err, loadErr) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	// refresh stores the loaded keys and reports the failed ones
+	if err = fetcher.Refresh(ctx, 123, 78); !errors.Is(err, loadErr) || mapCache.Map["123"] != "123" {
+		t.Fatalf("unexpected err: %v, mapCache: %v", err, mapCache.Map)
+	}
+}
+
+func TestMGetWithMetaSourceFirst(t *testing.T) {
+	mapCache := NewMapCache[string]()
+	loadErr := errors.New("error")
+	fetcher := New[int, string](mapCache).
+		WithStrategy(StrategySourceFirst).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			if key == 78 || key == 90 {
+				return "", loadErr
+			}
+			return fmt.Sprint(key), nil
+		}).Build()
+	_ = fetcher.Set(ctx, 78, "cached")
+	results, err := fetcher.MGetWithMeta(ctx, []int{123, 78, 90})
+	if err != nil || !slices.Equal(withoutMetadata(results), []Result[string]{
+		{Value: "123", Found: true, From: FromSource},
+		{Value: "cached", Found: true, From: FromCache},
+		{Value: "", Err: loadErr},
+	}) {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+	// mismatched batch length fails every key
+	fetcher = New[int, string](mapCache).
+		WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+			return []string{"1"}, nil
+		}).Build()
+	results, _ = fetcher.MGetWithMeta(ctx, []int{1, 2})
+	if results[0].Err == nil || results[1].Err == nil {
+		t.Fatalf("unexpected results: %v", results)
+	}
+}
+
+// withoutMetadata clears the metadata, age and TTL of results, which vary
+// between runs.
+func withoutMetadata[V any](results []Result[V]) []Result[V] {
+	cleared := make([]Result[V], len(results))
+	for i, result := range results {
+		result.Metadata = cache.Metadata{}
+		result.Age, result.TTL, result.HasTTL = 0, 0, false
+		cleared[i] = result
 	}
+	return cleared
 }
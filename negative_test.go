@@ -0,0 +1,101 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestNotFoundExpiration(t *testing.T) {
+	fetcher := New[int, string](memory.New[string]()).
+		WithExpiration(time.Minute).
+		WithNotFoundExpiration(20 * time.Millisecond).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			if key >= 100 {
+				return "", fmt.Errorf("key %d: %w", key, ErrNotFound)
+			}
+			return fmt.Sprint(key), nil
+		}).Build()
+	source := func() int64 { return fetcher.Stats().Loads }
+
+	// tombstone stored
+	if _, err := fetcher.Get(ctx, 123); !errors.Is(err, ErrNotFound) || source() != 1 {
+		t.Fatalf("unexpected err: %v, source: %d", err, source())
+	}
+	// answered from cache
+	if _, err := fetcher.Get(ctx, 123); !errors.Is(err, ErrNotFound) || source() != 1 {
+		t.Fatalf("unexpected err: %v, source: %d", err, source())
+	}
+	results, _ := fetcher.MGetWithMeta(ctx, []int{1, 123, 456})
+	if !results[0].Found || !errors.Is(results[1].Err, ErrNotFound) || !errors.Is(results[2].Err, ErrNotFound) || source() != 3 {
+		t.Fatalf("unexpected results: %v, source: %d", results, source())
+	}
+	values, err := fetcher.MGet(ctx, []int{1, 123, 456})
+	if err != nil || !slices.Equal(values, []string{"1", "", ""}) || source() != 3 {
+		t.Fatalf("unexpected values: %v, err: %v, source: %d", values, err, source())
+	}
+	// tombstone expired
+	time.Sleep(30 * time.Millisecond)
+	if _, err = fetcher.Get(ctx, 123); !errors.Is(err, ErrNotFound) || source() != 4 {
+		t.Fatalf("unexpected err: %v, source: %d", err, source())
+	}
+	// refresh caches tombstones without failing
+	if err = fetcher.Refresh(ctx, 1, 789); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, err = fetcher.Get(ctx, 789); !errors.Is(err, ErrNotFound) || source() != 4 {
+		t.Fatalf("unexpected err: %v, source: %d", err, source())
+	}
+}
+
+func TestNotFoundWithoutNegativeCache(t *testing.T) {
+	fetcher := New[int, string](memory.New[string]()).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return "", ErrNotFound
+		}).Build()
+	for i := 0; i < 2; i++ {
+		if _, err := fetcher.Get(ctx, 123); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	if fetcher.Stats().Loads != 2 {
+		t.Fatalf("unexpected source: %d", fetcher.Stats().Loads)
+	}
+	if err := fetcher.Refresh(ctx, 123); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestNotFoundExpirationWithoutMetadata(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("cache dropping metadata accepted")
+		}
+	}()
+	New[int, string](NewMapCache[string]()).
+		WithNotFoundExpiration(time.Minute).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return "", ErrNotFound
+		}).Build()
+}
+
+func TestNotFoundExpirationBatchLength(t *testing.T) {
+	fetcher := New[int, string](memory.New[string]()).
+		WithNotFoundExpiration(time.Minute).
+		WithResultBatchLoadFunc(func(ctx context.Context, keys []int) ([]LoadResult[string], error) {
+			return nil, nil
+		}).Build()
+	for i := 1; i <= 2; i++ {
+		if _, err := fetcher.Get(ctx, 123); err == nil || errors.Is(err, ErrNotFound) {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if fetcher.Stats().Loads != int64(i) {
+			t.Fatalf("unexpected source: %d", fetcher.Stats().Loads)
+		}
+	}
+}
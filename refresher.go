@@ -0,0 +1,170 @@
+package anycache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RefreshAheadOptions configures the refresh-ahead scheduler, zero fields take
+// their defaults.
+type RefreshAheadOptions struct {
+	// Interval is the period between scans of the tracked keys, 1s by default.
+	Interval time.Duration
+	// Ahead is how long before expiring a hot key is refreshed, twice the
+	// Interval by default.
+	Ahead time.Duration
+	// MinAccesses is the number of reads within an Interval making a key hot,
+	// 1 by default.
+	MinAccesses int64
+	// MaxKeys bounds the number of tracked keys, 10000 by default.
+	MaxKeys int
+	// BatchSize bounds the keys of one BatchLoad call, 100 by default.
+	BatchSize int
+	// Concurrency bounds the concurrent BatchLoad calls, 1 by default.
+	Concurrency int
+}
+
+type hotKey[K any] struct {
+	key       K
+	accesses  int64
+	expiresAt time.Time
+}
+
+// refresher counts the reads of cached keys and reloads the hot ones shortly
+// before they expire.
+type refresher[K any, V any] struct {
+	options RefreshAheadOptions
+	refresh func(ctx context.Context, keys ...K) error
+
+	mu   sync.Mutex
+	keys map[string]*hotKey[K]
+
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+func newRefresher[K any, V any](options RefreshAheadOptions) *refresher[K, V] {
+	if options.Interval <= 0 {
+		options.Interval = time.Second
+	}
+	if options.Ahead <= 0 {
+		options.Ahead = 2 * options.Interval
+	}
+	if options.MinAccesses <= 0 {
+		options.MinAccesses = 1
+	}
+	if options.MaxKeys <= 0 {
+		options.MaxKeys = 10000
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	return &refresher[K, V]{
+		options: options,
+		keys:    make(map[string]*hotKey[K]),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// track records a read of key, whose cached value expires at expiresAt.
+func (r *refresher[K, V]) track(cacheKey string, key K, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if hk, ok := r.keys[cacheKey]; ok {
+		hk.accesses++
+		hk.expiresAt = expiresAt
+		return
+	}
+	if len(r.keys) < r.options.MaxKeys {
+		r.keys[cacheKey] = &hotKey[K]{key: key, accesses: 1, expiresAt: expiresAt}
+	}
+}
+
+// start launches the scheduler, only the first call does.
+func (r *refresher[K, V]) start() {
+	r.startOnce.Do(func() {
+		go r.run()
+	})
+}
+
+func (r *refresher[K, V]) run() {
+	defer close(r.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	ticker := time.NewTicker(r.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			r.scan(ctx, now)
+		}
+	}
+}
+
+// close stops the scheduler and waits for it to return. A scheduler never
+// started cannot be started afterwards.
+func (r *refresher[K, V]) close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.startOnce.Do(func() {
+			close(r.done)
+		})
+		<-r.done
+	})
+}
+
+// scan refreshes the hot keys expiring within Ahead and starts a new counting
+// window, forgetting the keys not read during the last one.
+func (r *refresher[K, V]) scan(ctx context.Context, now time.Time) {
+	var due []K
+	r.mu.Lock()
+	for cacheKey, hk := range r.keys {
+		switch {
+		case hk.accesses >= r.options.MinAccesses && hk.expiresAt.After(now) && hk.expiresAt.Sub(now) <= r.options.Ahead:
+			due = append(due, hk.key)
+			delete(r.keys, cacheKey)
+		case hk.accesses == 0 || !hk.expiresAt.After(now):
+			delete(r.keys, cacheKey)
+		default:
+			hk.accesses = 0
+		}
+	}
+	r.mu.Unlock()
+
+	sem := make(chan struct{}, r.options.Concurrency)
+	var wg sync.WaitGroup
+	for len(due) > 0 {
+		n := min(len(due), r.options.BatchSize)
+		batch := due[:n]
+		due = due[n:]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_ = r.refresh(ctx, batch...)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package anycache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestRefreshAhead(t *testing.T) {
+	var version, batches atomic.Int64
+	fetcher := New[int, string](memory.New[string]()).
+		WithExpiration(100 * time.Millisecond).
+		WithRefreshAhead(RefreshAheadOptions{
+			Interval:    10 * time.Millisecond,
+			Ahead:       50 * time.Millisecond,
+			MinAccesses: 2,
+		}).
+		WithBatchLoadFunc(func(ctx context.Context, keys []int) ([]string, error) {
+			batches.Add(1)
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				values[i] = fmt.Sprint(key, "-", version.Load())
+			}
+			return values, nil
+		}).Build()
+	defer fetcher.Close()
+
+	if values, _ := fetcher.MGet(ctx, []int{1, 2}); !slices.Equal(values, []string{"1-0", "2-0"}) {
+		t.Fatalf("unexpected values: %v", values)
+	}
+	version.Store(1)
+	// key 1 is read often enough to be refreshed before expiring, key 2 is not
+	deadline := time.Now().Add(time.Second)
+	for {
+		v, err := fetcher.Get(ctx, 1)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if v == "1-1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("value not refreshed")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if batches.Load() != 2 {
+		t.Fatalf("unexpected batches: %d", batches.Load())
+	}
+	if stats := fetcher.Stats(); stats.Refreshes != 1 || stats.RefreshedKeys != 1 {
+		t.Fatalf("unexpected refreshes: %d, keys: %d", stats.Refreshes, stats.RefreshedKeys)
+	}
+
+	if err := fetcher.Close(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		_, _ = fetcher.Get(ctx, 1)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fetcher.Stats().Refreshes != 1 {
+		t.Fatalf("refreshed after close")
+	}
+}
+
+func TestRefreshAheadBuildTwice(t *testing.T) {
+	builder := New[int, string](memory.New[string]()).
+		WithRefreshAhead(RefreshAheadOptions{Interval: time.Millisecond}).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		})
+	builder.Build()
+	fetcher := builder.Build()
+	if err := fetcher.Close(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := fetcher.Close(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestRefreshAheadCloseUnbuilt(t *testing.T) {
+	r := newRefresher[int, string](RefreshAheadOptions{})
+	r.close()
+	r.start()
+}
+
+func TestRefreshAheadWithoutMetadata(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("cache dropping metadata accepted")
+		}
+	}()
+	New[int, string](ttlCache{memory.New[string]()}).
+		WithRefreshAhead(RefreshAheadOptions{}).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}).Build()
+}
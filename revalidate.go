@@ -0,0 +1,74 @@
+package anycache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+// revalidator tracks the keys being reloaded in the background so that each
+// key has at most one reload in flight.
+type revalidator struct {
+	mu       sync.Mutex
+	inflight map[string]struct{}
+}
+
+func newRevalidator() *revalidator {
+	return &revalidator{inflight: make(map[string]struct{})}
+}
+
+// acquire marks the keys not in flight yet as in flight and returns their
+// indices.
+func (r *revalidator) acquire(cacheKeys []string) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var acquired []int
+	for i, key := range cacheKeys {
+		if _, ok := r.inflight[key]; !ok {
+			r.inflight[key] = struct{}{}
+			acquired = append(acquired, i)
+		}
+	}
+	return acquired
+}
+
+func (r *revalidator) release(cacheKeys []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range cacheKeys {
+		delete(r.inflight, key)
+	}
+}
+
+// stale reports whether e is past its soft expiration.
+func stale[V any](e cache.Entry[V], now time.Time) bool {
+	softExpiresAt := cache.MetadataOf(e).SoftExpiresAt
+	return !softExpiresAt.IsZero() && !now.Before(softExpiresAt)
+}
+
+// expired reports whether e is past its expiration.
+func expired[V any](e cache.Entry[V], now time.Time) bool {
+	expiresAt := cache.MetadataOf(e).ExpiresAt
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
+
+// revalidate reloads keys in the background, skipping those already being
+// reloaded.
+func (a *anyCache[K, V]) revalidate(ctx context.Context, keys []K) {
+	cacheKeys := a.buildKeys(keys)
+	acquired := a.revalidator.acquire(cacheKeys)
+	if len(acquired) == 0 {
+		return
+	}
+	reloadKeys, reloadCacheKeys := make([]K, len(acquired)), make([]string, len(acquired))
+	for i, index := range acquired {
+		reloadKeys[i], reloadCacheKeys[i] = keys[index], cacheKeys[index]
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer a.revalidator.release(reloadCacheKeys)
+		_ = a.backgroundRefresh(ctx, reloadKeys...)
+	}()
+}
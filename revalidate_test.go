@@ -0,0 +1,82 @@
+package anycache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestSoftExpiration(t *testing.T) {
+	var version, loads atomic.Int64
+	release := make(chan struct{})
+	var blocked atomic.Bool
+	fetcher := New[int, string](memory.New[string]()).
+		WithExpiration(time.Minute).
+		WithSoftExpiration(20 * time.Millisecond).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			loads.Add(1)
+			if blocked.Load() {
+				<-release
+			}
+			return fmt.Sprint(key, "-", version.Load()), nil
+		}).Build()
+
+	if v, _ := fetcher.Get(ctx, 1); v != "1-0" {
+		t.Fatalf("unexpected value: %s", v)
+	}
+	if values, _ := fetcher.MGet(ctx, []int{1, 2}); !slices.Equal(values, []string{"1-0", "2-0"}) || loads.Load() != 2 {
+		t.Fatalf("unexpected values: %v, loads: %d", values, loads.Load())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	version.Store(1)
+	blocked.Store(true)
+	// stale values are served while a single reload per key runs
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if values, _ := fetcher.MGet(ctx, []int{1, 2}); !slices.Equal(values, []string{"1-0", "2-0"}) {
+				t.Errorf("unexpected values: %v", values)
+			}
+			if v, _ := fetcher.Get(ctx, 1); v != "1-0" {
+				t.Errorf("unexpected value: %s", v)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if values, _ := fetcher.MGet(ctx, []int{1, 2}); slices.Equal(values, []string{"1-1", "2-1"}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("value not revalidated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if loads.Load() != 4 {
+		t.Fatalf("unexpected loads: %d", loads.Load())
+	}
+}
+
+func TestSoftExpirationWithoutMetadata(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("cache dropping metadata accepted")
+		}
+	}()
+	New[int, string](ttlCache{memory.New[string]()}).
+		WithSoftExpiration(time.Millisecond).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}).Build()
+}
@@ -0,0 +1,58 @@
+package anycache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// group coalesces concurrent calls sharing a key into one execution.
+type group[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+func newGroup[V any]() *group[V] {
+	return &group[V]{calls: make(map[string]*call[V])}
+}
+
+// do runs fn once for the concurrent calls sharing key. fn runs in its own
+// goroutine, under a context no caller cancels, while every caller waits for
+// it or its own ctx to be done. A panic of fn is returned as an error.
+func (g *group[V]) do(ctx context.Context, key string, fn func(ctx context.Context) (V, error)) (V, error) {
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call[V]{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(context.WithoutCancel(ctx), key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (g *group[V]) run(ctx context.Context, key string, c *call[V], fn func(ctx context.Context) (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("anycache: load panicked: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn(ctx)
+}
@@ -0,0 +1,135 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestGetSingleflight(t *testing.T) {
+	memCache := memory.New[string]()
+	var mu sync.Mutex
+	fail := true
+	release := make(chan struct{})
+	fetcher := New[int, string](memCache).
+		WithSingleflight(true).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			<-release
+			mu.Lock()
+			defer mu.Unlock()
+			if fail {
+				return "", errors.New("error")
+			}
+			return fmt.Sprint(key), nil
+		}).Build()
+
+	get := func() []error {
+		var wg sync.WaitGroup
+		errs := make([]error, 10)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = fetcher.Get(ctx, 123)
+			}(i)
+		}
+		time.Sleep(20 * time.Millisecond)
+		release <- struct{}{}
+		wg.Wait()
+		return errs
+	}
+	// shared failure
+	for _, err := range get() {
+		if err == nil {
+			t.Fatalf("unexpected success")
+		}
+	}
+	if fetcher.Stats().Loads != 1 {
+		t.Fatalf("unexpected source: %d", fetcher.Stats().Loads)
+	}
+	// shared success
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	_ = memCache.Del(ctx, "123")
+	for _, err := range get() {
+		if err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+	}
+	if e, err := memCache.Get(ctx, "123"); fetcher.Stats().Loads != 2 || err != nil || e.Value() != "123" {
+		t.Fatalf("unexpected source: %d, err: %v", fetcher.Stats().Loads, err)
+	}
+}
+
+func TestGetSingleflightCanceled(t *testing.T) {
+	release := make(chan struct{})
+	fetcher := New[int, string](memory.New[string]()).
+		WithSingleflight(true).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			select {
+			case <-release:
+				return fmt.Sprint(key), nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}).Build()
+
+	firstCtx, cancel := context.WithCancel(ctx)
+	first := make(chan error)
+	go func() {
+		_, err := fetcher.Get(firstCtx, 123)
+		first <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	second := make(chan error)
+	go func() {
+		v, err := fetcher.Get(ctx, 123)
+		if err == nil && v != "123" {
+			err = fmt.Errorf("unexpected value: %s", v)
+		}
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// the first caller gives up without failing the shared load
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if fetcher.Stats().Loads != 1 {
+		t.Fatalf("unexpected source: %d", fetcher.Stats().Loads)
+	}
+}
+
+func TestGetSingleflightPanic(t *testing.T) {
+	fetcher := New[int, string](memory.New[string]()).
+		WithSingleflight(true).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			panic("boom")
+		}).Build()
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = fetcher.Get(ctx, 123)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			t.Fatalf("unexpected success")
+		}
+	}
+}
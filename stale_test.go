@@ -0,0 +1,129 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestStaleIfError(t *testing.T) {
+	errLoad := errors.New("load failed")
+	var failing, hanging atomic.Bool
+	newFetcher := func(c cache.Cacher[string], strategy strategy) Fetcher[int, string] {
+		return New[int, string](c).
+			WithStrategy(strategy).
+			WithExpiration(20 * time.Millisecond).
+			WithStaleIfError(time.Minute).
+			WithLoadTimeout(10 * time.Millisecond).
+			WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+				if hanging.Load() {
+					<-ctx.Done()
+					return "", ctx.Err()
+				}
+				if failing.Load() {
+					return "", errLoad
+				}
+				return fmt.Sprint(key), nil
+			}).Build()
+	}
+	c := memory.New[string]()
+	fetcher := newFetcher(c, StrategyCacheFirst)
+	if values, err := fetcher.MGet(ctx, []int{1, 2}); err != nil || values[0] != "1" || values[1] != "2" {
+		t.Fatalf("unexpected values: %v, err: %v", values, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	failing.Store(true)
+	if v, err := fetcher.Get(ctx, 1); err != nil || v != "1" {
+		t.Fatalf("unexpected value: %s, err: %v", v, err)
+	}
+	results, err := fetcher.MGetWithMeta(ctx, []int{1, 2, 3})
+	if err != nil || !results[0].Stale || results[0].Value != "1" || !results[1].Stale || !errors.Is(results[2].Err, errLoad) {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+	failing.Store(false)
+	hanging.Store(true)
+	// a timed out load serves the stale value too
+	if v, err := fetcher.Get(ctx, 2); err != nil || v != "2" {
+		t.Fatalf("unexpected value: %s, err: %v", v, err)
+	}
+
+	hanging.Store(false)
+	failing.Store(true)
+	if results, err := newFetcher(c, StrategySourceFirst).MGetWithMeta(ctx, []int{1}); err != nil || !results[0].Stale || results[0].Value != "1" {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+	// expired values are misses for the cache only strategy
+	if _, err := newFetcher(c, StrategyCacheOnly).Get(ctx, 1); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if results, err := newFetcher(c, StrategyCacheOnly).MGetWithMeta(ctx, []int{1}); err != nil || !errors.Is(results[0].Err, cache.ErrNotFound) {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+
+	failing.Store(false)
+	if results, err := fetcher.MGetWithMeta(ctx, []int{1}); err != nil || results[0].Stale || results[0].Value != "1" {
+		t.Fatalf("unexpected results: %v, err: %v", results, err)
+	}
+}
+
+// ttlCache honours the expiration of the entries but drops their metadata.
+type ttlCache struct {
+	*memory.Cache[string]
+}
+
+func (c ttlCache) Get(ctx context.Context, key string) (cache.Entry[string], error) {
+	e, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewEntry(key, e.Value(), e.Expiration()), nil
+}
+
+func (c ttlCache) MGet(ctx context.Context, keys []string) ([]cache.Entry[string], error) {
+	entries, err := c.Cache.MGet(ctx, keys)
+	for i, e := range entries {
+		if e != nil {
+			entries[i] = cache.NewEntry(e.Key(), e.Value(), e.Expiration())
+		}
+	}
+	return entries, err
+}
+
+func (c ttlCache) KeepsMetadata() bool {
+	return false
+}
+
+func TestStaleIfErrorWithoutMetadata(t *testing.T) {
+	c := ttlCache{memory.New[string]()}
+	builder := New[int, string](c).
+		WithExpiration(20 * time.Millisecond).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("cache dropping metadata accepted")
+			}
+		}()
+		builder.WithStaleIfError(time.Minute).Build()
+	}()
+
+	// without a grace, entries expire with their values
+	fetcher := builder.WithStaleIfError(0).Build()
+	_, _ = fetcher.Get(ctx, 1)
+	if _, err := c.Get(ctx, "1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get(ctx, "1"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("expired value kept: %v", err)
+	}
+}
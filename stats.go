@@ -0,0 +1,237 @@
+package anycache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// StatsProvider exposes the counters of a fetcher.
+type StatsProvider interface {
+	// Stats returns a snapshot of the counters accumulated since the fetcher
+	// was built or the last ResetStats.
+	Stats() Stats
+	ResetStats()
+}
+
+// Stats is a snapshot of the counters of a fetcher. Loads and LoadErrors
+// count the keys loaded from the source by Get and MGet calls: on cache misses
+// and failures, and on every call with StrategySourceFirst. A key the source
+// reports not found is not a load error. LoaderCalls counts the Load and BatchLoad calls of any
+// kind. Refreshes count the reloads by Refresh, stale values and the
+// refresh-ahead scheduler, RefreshErrors those failing for any key.
+// CacheErrors counts the failed cache operations, misses excluded.
+type Stats struct {
+	Hits          int64
+	Misses        int64
+	Loads         int64
+	LoadErrors    int64
+	LoaderCalls   int64
+	CacheErrors   int64
+	Sets          int64
+	Deletes       int64
+	Refreshes     int64
+	RefreshedKeys int64
+	RefreshErrors int64
+	// LoadLatency is the distribution of the durations of Load and BatchLoad
+	// calls.
+	LoadLatency Histogram
+	// BatchSizes is the distribution of the number of keys of BatchLoad
+	// calls.
+	BatchSizes SizeHistogram
+	// CacheLatency is the distribution of the durations of the cache
+	// operations.
+	CacheLatency CacheLatency
+}
+
+// CacheLatency holds the latency distribution of each cache operation.
+type CacheLatency struct {
+	Get  Histogram
+	MGet Histogram
+	Set  Histogram
+	Del  Histogram
+}
+
+// HitRatio is the ratio of hits to cache lookups, zero without lookups.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Histogram is a distribution of durations. Counts[i] is the number of
+// durations up to Bounds[i] and greater than the previous bound, the last
+// count being the number of durations greater than every bound.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+}
+
+// SizeHistogram is a distribution of sizes, laid out like a Histogram.
+type SizeHistogram struct {
+	Bounds []int64
+	Counts []int64
+	Count  int64
+	Sum    int64
+}
+
+var latencyBounds = []int64{
+	int64(time.Millisecond),
+	int64(2500 * time.Microsecond),
+	int64(5 * time.Millisecond),
+	int64(10 * time.Millisecond),
+	int64(25 * time.Millisecond),
+	int64(50 * time.Millisecond),
+	int64(100 * time.Millisecond),
+	int64(250 * time.Millisecond),
+	int64(500 * time.Millisecond),
+	int64(time.Second),
+	int64(2500 * time.Millisecond),
+	int64(5 * time.Second),
+	int64(10 * time.Second),
+}
+
+var sizeBounds = []int64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+type histogram struct {
+	bounds []int64
+	counts []int64
+	count  int64
+	sum    int64
+}
+
+func newHistogram(bounds []int64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(v int64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.count, 1)
+	atomic.AddInt64(&h.sum, v)
+}
+
+func (h *histogram) observeSince(start time.Time) {
+	h.observe(int64(time.Since(start)))
+}
+
+// snapshot returns the bucket counts, count and sum of h.
+func (h *histogram) snapshot() ([]int64, int64, int64) {
+	counts := make([]int64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = atomic.LoadInt64(&h.counts[i])
+	}
+	return counts, atomic.LoadInt64(&h.count), atomic.LoadInt64(&h.sum)
+}
+
+func (h *histogram) durations() Histogram {
+	counts, count, sum := h.snapshot()
+	bounds := make([]time.Duration, len(h.bounds))
+	for i, bound := range h.bounds {
+		bounds[i] = time.Duration(bound)
+	}
+	return Histogram{Bounds: bounds, Counts: counts, Count: count, Sum: time.Duration(sum)}
+}
+
+func (h *histogram) sizes() SizeHistogram {
+	counts, count, sum := h.snapshot()
+	return SizeHistogram{Bounds: h.bounds, Counts: counts, Count: count, Sum: sum}
+}
+
+func (h *histogram) reset() {
+	for i := range h.counts {
+		atomic.StoreInt64(&h.counts[i], 0)
+	}
+	atomic.StoreInt64(&h.count, 0)
+	atomic.StoreInt64(&h.sum, 0)
+}
+
+type stats struct {
+	hits        int64
+	misses      int64
+	loads       int64
+	loadErrors  int64
+	loaderCalls int64
+	cacheErrors int64
+	sets        int64
+	deletes     int64
+
+	refreshes     int64
+	refreshedKeys int64
+	refreshErrors int64
+
+	loadLatency *histogram
+	batchSizes  *histogram
+	getLatency  *histogram
+	mGetLatency *histogram
+	setLatency  *histogram
+	delLatency  *histogram
+}
+
+func newStats() *stats {
+	return &stats{
+		loadLatency: newHistogram(latencyBounds),
+		batchSizes:  newHistogram(sizeBounds),
+		getLatency:  newHistogram(latencyBounds),
+		mGetLatency: newHistogram(latencyBounds),
+		setLatency:  newHistogram(latencyBounds),
+		delLatency:  newHistogram(latencyBounds),
+	}
+}
+
+func (s *stats) histograms() []*histogram {
+	return []*histogram{s.loadLatency, s.batchSizes, s.getLatency, s.mGetLatency, s.setLatency, s.delLatency}
+}
+
+func (a *anyCache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:        atomic.LoadInt64(&a.stats.hits),
+		Misses:      atomic.LoadInt64(&a.stats.misses),
+		Loads:       atomic.LoadInt64(&a.stats.loads),
+		LoadErrors:  atomic.LoadInt64(&a.stats.loadErrors),
+		LoaderCalls: atomic.LoadInt64(&a.stats.loaderCalls),
+		CacheErrors: atomic.LoadInt64(&a.stats.cacheErrors),
+		Sets:        atomic.LoadInt64(&a.stats.sets),
+		Deletes:     atomic.LoadInt64(&a.stats.deletes),
+		LoadLatency: a.stats.loadLatency.durations(),
+		BatchSizes:  a.stats.batchSizes.sizes(),
+		CacheLatency: CacheLatency{
+			Get:  a.stats.getLatency.durations(),
+			MGet: a.stats.mGetLatency.durations(),
+			Set:  a.stats.setLatency.durations(),
+			Del:  a.stats.delLatency.durations(),
+		},
+		Refreshes:     atomic.LoadInt64(&a.stats.refreshes),
+		RefreshedKeys: atomic.LoadInt64(&a.stats.refreshedKeys),
+		RefreshErrors: atomic.LoadInt64(&a.stats.refreshErrors),
+	}
+}
+
+func (a *anyCache[K, V]) ResetStats() {
+	for _, counter := range []*int64{
+		&a.stats.hits,
+		&a.stats.misses,
+		&a.stats.loads,
+		&a.stats.loadErrors,
+		&a.stats.loaderCalls,
+		&a.stats.cacheErrors,
+		&a.stats.sets,
+		&a.stats.deletes,
+		&a.stats.refreshes,
+		&a.stats.refreshedKeys,
+		&a.stats.refreshErrors,
+	} {
+		atomic.StoreInt64(counter, 0)
+	}
+	for _, h := range a.stats.histograms() {
+		h.reset()
+	}
+}
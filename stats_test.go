@@ -0,0 +1,86 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+func TestStats(t *testing.T) {
+	loadErr := errors.New("error")
+	fetcher := New[int, string](memory.New[string]()).
+		WithNotFoundExpiration(time.Minute).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			switch key {
+			case 78:
+				return "", loadErr
+			case 90:
+				return "", ErrNotFound
+			}
+			return fmt.Sprint(key), nil
+		}).Build()
+
+	_, _ = fetcher.Get(ctx, 123)
+	_, _ = fetcher.Get(ctx, 123)
+	_, _ = fetcher.MGet(ctx, []int{123, 456, 78, 90})
+	_ = fetcher.Set(ctx, 1, "1")
+	_ = fetcher.Del(ctx, 1, 2)
+	_ = fetcher.Refresh(ctx, 123, 78)
+
+	stats := fetcher.Stats()
+	want := Stats{
+		Hits:          2,
+		Misses:        4,
+		Loads:         4,
+		LoadErrors:    1,
+		LoaderCalls:   3,
+		Sets:          5,
+		Deletes:       2,
+		Refreshes:     1,
+		RefreshedKeys: 2,
+		RefreshErrors: 1,
+	}
+	latency, sizes, cacheLatency := stats.LoadLatency, stats.BatchSizes, stats.CacheLatency
+	stats.LoadLatency, stats.BatchSizes, stats.CacheLatency = Histogram{}, SizeHistogram{}, CacheLatency{}
+	if !reflect.DeepEqual(stats, want) || stats.HitRatio() != 1.0/3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	var count int64
+	for _, c := range latency.Counts {
+		count += c
+	}
+	if latency.Count != 3 || count != 3 || len(latency.Counts) != len(latency.Bounds)+1 {
+		t.Fatalf("unexpected latency: %+v", latency)
+	}
+	// batches of 3 and 2 keys
+	if sizes.Count != 2 || sizes.Sum != 5 || sizes.Counts[1] != 1 || sizes.Counts[2] != 1 {
+		t.Fatalf("unexpected batch sizes: %+v", sizes)
+	}
+	if cacheLatency.Get.Count != 2 || cacheLatency.MGet.Count != 1 || cacheLatency.Set.Count != 4 || cacheLatency.Del.Count != 1 {
+		t.Fatalf("unexpected cache latency: %+v", cacheLatency)
+	}
+
+	fetcher.ResetStats()
+	if stats = fetcher.Stats(); stats.Hits != 0 || stats.Loads != 0 || stats.LoadLatency.Count != 0 || stats.Refreshes != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestStatsCacheErrors(t *testing.T) {
+	mapCache := NewMapCache[string]()
+	mapCache.Fail = true
+	fetcher := New[int, string](mapCache).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			return fmt.Sprint(key), nil
+		}).Build()
+	_, _ = fetcher.Get(ctx, 123)
+	_, _ = fetcher.MGet(ctx, []int{123, 456})
+	if stats := fetcher.Stats(); stats.CacheErrors != 4 || stats.Sets != 0 || stats.Loads != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,167 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/xianlianghe0123/anycache/cache"
+)
+
+// Tracer starts the spans of cache and loader operations. It follows the shape
+// of the OpenTelemetry trace API, so that an adapter only converts attributes:
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...anycache.Attribute) (context.Context, anycache.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an operation started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError records that the operation failed with err.
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key value pair describing a span, Value is a string, an int
+// or a bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+const (
+	AttrNamespace  = "anycache.namespace"
+	AttrStrategy   = "anycache.strategy"
+	AttrKeys       = "anycache.keys"
+	AttrHits       = "anycache.hits"
+	AttrFailedKeys = "anycache.failed_keys"
+)
+
+const (
+	SpanCacheGet  = "anycache.cache.get"
+	SpanCacheMGet = "anycache.cache.mget"
+	SpanCacheSet  = "anycache.cache.set"
+	SpanCacheDel  = "anycache.cache.del"
+	SpanLoad      = "anycache.load"
+	SpanBatchLoad = "anycache.batch_load"
+)
+
+// WithTracer traces the cache operations and the loader calls with tracer.
+func (a *anyCache[K, V]) WithTracer(tracer Tracer) IAnyCache[K, V] {
+	a.tracer.tracer = tracer
+	return a
+}
+
+// tracer starts spans with the attributes of the fetcher, doing nothing
+// without a Tracer.
+type tracer struct {
+	tracer Tracer
+	attrs  []Attribute
+}
+
+func (t *tracer) start(ctx context.Context, name string, keys int) (context.Context, Span) {
+	if t.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	attrs := append(slices.Clip(t.attrs), Attribute{Key: AttrKeys, Value: keys})
+	return t.tracer.Start(ctx, name, attrs...)
+}
+
+// endSpan records err unless it reports a missing key, sets attrs and ends
+// span.
+func endSpan(span Span, err error, attrs ...Attribute) {
+	if err != nil && !errors.Is(err, cache.ErrNotFound) && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+	}
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+	span.End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// instrumentedCacher traces and times the operations of the cache it wraps,
+// and counts its successful writes and its failures.
+type instrumentedCacher[V any] struct {
+	cacher cache.Cacher[V]
+	stats  *stats
+	tracer *tracer
+}
+
+func (c *instrumentedCacher[V]) Get(ctx context.Context, key string) (cache.Entry[V], error) {
+	ctx, span := c.tracer.start(ctx, SpanCacheGet, 1)
+	start := time.Now()
+	e, err := c.cacher.Get(ctx, key)
+	c.stats.getLatency.observeSince(start)
+	c.count(err)
+	hits := 0
+	if err == nil {
+		hits = 1
+	}
+	endSpan(span, err, Attribute{Key: AttrHits, Value: hits})
+	return e, err
+}
+
+func (c *instrumentedCacher[V]) MGet(ctx context.Context, keys []string) ([]cache.Entry[V], error) {
+	ctx, span := c.tracer.start(ctx, SpanCacheMGet, len(keys))
+	start := time.Now()
+	entries, err := c.cacher.MGet(ctx, keys)
+	c.stats.mGetLatency.observeSince(start)
+	c.count(err)
+	hits := 0
+	for _, e := range entries {
+		if e != nil {
+			hits++
+		}
+	}
+	endSpan(span, err, Attribute{Key: AttrHits, Value: hits})
+	return entries, err
+}
+
+func (c *instrumentedCacher[V]) Set(ctx context.Context, entries ...cache.Entry[V]) error {
+	ctx, span := c.tracer.start(ctx, SpanCacheSet, len(entries))
+	start := time.Now()
+	err := c.cacher.Set(ctx, entries...)
+	c.stats.setLatency.observeSince(start)
+	if err == nil {
+		atomic.AddInt64(&c.stats.sets, int64(len(entries)))
+	}
+	c.count(err)
+	endSpan(span, err)
+	return err
+}
+
+func (c *instrumentedCacher[V]) Del(ctx context.Context, keys ...string) error {
+	ctx, span := c.tracer.start(ctx, SpanCacheDel, len(keys))
+	start := time.Now()
+	err := c.cacher.Del(ctx, keys...)
+	c.stats.delLatency.observeSince(start)
+	if err == nil {
+		atomic.AddInt64(&c.stats.deletes, int64(len(keys)))
+	}
+	c.count(err)
+	endSpan(span, err)
+	return err
+}
+
+func (c *instrumentedCacher[V]) KeepsMetadata() bool {
+	return cache.KeepsMetadata(c.cacher)
+}
+
+func (c *instrumentedCacher[V]) count(err error) {
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		atomic.AddInt64(&c.stats.cacheErrors, 1)
+	}
+}
@@ -0,0 +1,98 @@
+package anycache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/xianlianghe0123/anycache/cache/memory"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &recordedSpan{name: name, attrs: make(map[string]any)}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+func TestTracer(t *testing.T) {
+	loadErr := errors.New("error")
+	tracer := &recordingTracer{}
+	fetcher := New[int, string](memory.New[string]()).
+		WithNameSpace("users").
+		WithTracer(tracer).
+		WithLoadFunc(func(ctx context.Context, key int) (string, error) {
+			if key == 78 {
+				return "", loadErr
+			}
+			return fmt.Sprint(key), nil
+		}).Build()
+
+	_, _ = fetcher.Get(ctx, 123)
+	_, _ = fetcher.MGet(ctx, []int{123, 456, 78})
+	_ = fetcher.Del(ctx, 123)
+
+	var names []string
+	for _, s := range tracer.spans {
+		names = append(names, s.name)
+		if !s.ended || s.attrs[AttrNamespace] != "users" || s.attrs[AttrStrategy] != "cache_first" {
+			t.Fatalf("unexpected span: %+v", s)
+		}
+	}
+	if !slices.Equal(names, []string{
+		SpanCacheGet, SpanLoad, SpanCacheSet,
+		SpanCacheMGet, SpanBatchLoad, SpanCacheSet,
+		SpanCacheDel,
+	}) {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+	// a miss is not an error
+	if get := tracer.spans[0]; get.err != nil || get.attrs[AttrKeys] != 1 || get.attrs[AttrHits] != 0 {
+		t.Fatalf("unexpected span: %+v", get)
+	}
+	if mGet := tracer.spans[3]; mGet.attrs[AttrKeys] != 3 || mGet.attrs[AttrHits] != 1 {
+		t.Fatalf("unexpected span: %+v", mGet)
+	}
+	if batchLoad := tracer.spans[4]; batchLoad.attrs[AttrKeys] != 2 || batchLoad.attrs[AttrFailedKeys] != 1 {
+		t.Fatalf("unexpected span: %+v", batchLoad)
+	}
+	if set := tracer.spans[5]; set.attrs[AttrKeys] != 1 {
+		t.Fatalf("unexpected span: %+v", set)
+	}
+
+	tracer.spans = nil
+	if _, err := fetcher.Get(ctx, 78); !errors.Is(err, loadErr) || !errors.Is(tracer.spans[1].err, loadErr) {
+		t.Fatalf("unexpected err: %v, spans: %+v", err, tracer.spans)
+	}
+}
@@ -0,0 +1,57 @@
+package anycache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLLoader(t *testing.T) {
+	ttlOf := func(key int) time.Duration {
+		switch key % 3 {
+		case 0:
+			return NoExpiration
+		case 1:
+			return 5 * time.Second
+		default:
+			return 0
+		}
+	}
+	c := &expirationCache{MapCache: NewMapCache[string](), expirations: map[string]time.Duration{}}
+	fetcher := New[int, string](c).
+		WithExpiration(time.Minute).
+		WithTTLLoadFunc(func(ctx context.Context, key int) (string, time.Duration, error) {
+			return fmt.Sprint(key), ttlOf(key), nil
+		}).Build()
+	// single load
+	_, _ = fetcher.Get(ctx, 1)
+	// batch synthesized from the ttl loader
+	_, _ = fetcher.MGet(ctx, []int{2, 3})
+	// set keeps the fetcher expiration
+	_ = fetcher.Set(ctx, 4, "4")
+	for key, want := range map[string]time.Duration{"1": 5 * time.Second, "2": time.Minute, "3": 0, "4": time.Minute} {
+		if c.expirations[key] != want {
+			t.Fatalf("unexpected expiration of %s: %s, want %s", key, c.expirations[key], want)
+		}
+	}
+
+	c = &expirationCache{MapCache: NewMapCache[string](), expirations: map[string]time.Duration{}}
+	fetcher = New[int, string](c).
+		WithExpiration(time.Minute).
+		WithResultBatchLoadFunc(func(ctx context.Context, keys []int) ([]LoadResult[string], error) {
+			results := make([]LoadResult[string], len(keys))
+			for i, k := range keys {
+				results[i] = LoadResult[string]{Value: fmt.Sprint(k), TTL: ttlOf(k)}
+			}
+			return results, nil
+		}).Build()
+	// single load synthesized from the batch loader
+	_, _ = fetcher.Get(ctx, 1)
+	_ = fetcher.Refresh(ctx, 2, 3)
+	for key, want := range map[string]time.Duration{"1": 5 * time.Second, "2": time.Minute, "3": 0} {
+		if c.expirations[key] != want {
+			t.Fatalf("unexpected expiration of %s: %s, want %s", key, c.expirations[key], want)
+		}
+	}
+}